@@ -0,0 +1,310 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/openai/openai-go/v3"
+)
+
+// StreamAccumulator assembles the chunks of a streamed chat completion into Genkit messages.
+// The plugin streams with it, and it works the same for chunks read with the OpenAI SDK:
+//
+//	var acc azureaifoundry.StreamAccumulator
+//	for stream.Next() {
+//		parts, err := acc.Add(stream.Current())
+//		if err != nil {
+//			return err
+//		}
+//		// Render parts as they arrive
+//	}
+//	msg, err := acc.Message()
+//	usage := acc.Usage()
+//
+// The zero value is ready to use. With "n" > 1 every choice is accumulated separately.
+type StreamAccumulator struct {
+	// PartialToolCalls makes Add also return in-progress tool requests (metadata "partial"
+	// and "arguments") once their name is known
+	PartialToolCalls bool
+	// MaxToolArgumentBytes caps the arguments of a single tool call (default 1 MiB)
+	MaxToolArgumentBytes int
+	// AudioContentType is the MIME type of spoken output (default audio/wav)
+	AudioContentType string
+
+	choices           map[int]*choiceAccumulator
+	usage             *ai.GenerationUsage
+	systemFingerprint string
+	serviceTier       string
+}
+
+// toolCallAccumulator holds tool call information during streaming
+type toolCallAccumulator struct {
+	id        string
+	name      string
+	arguments strings.Builder
+}
+
+// choiceAccumulator holds the streamed state of a single choice
+type choiceAccumulator struct {
+	text         strings.Builder
+	reasoning    strings.Builder
+	refusal      strings.Builder
+	audio        streamedAudio
+	finishReason string
+	toolCalls    map[int]*toolCallAccumulator
+	logprobs     []TokenLogprob
+}
+
+// Add feeds a chunk and returns the new parts of the first choice, in the order reasoning,
+// text, audio, refusal and tool calls, ready to forward to a streaming callback
+func (s *StreamAccumulator) Add(chunk openai.ChatCompletionChunk) ([]*ai.Part, error) {
+	if s.choices == nil {
+		s.choices = make(map[int]*choiceAccumulator)
+	}
+	maxArgBytes := s.MaxToolArgumentBytes
+	if maxArgBytes <= 0 {
+		maxArgBytes = defaultMaxToolArgumentBytes
+	}
+
+	if chunk.SystemFingerprint != "" {
+		s.systemFingerprint = chunk.SystemFingerprint
+	}
+	if chunk.ServiceTier != "" {
+		s.serviceTier = string(chunk.ServiceTier)
+	}
+	// The terminal chunk carries usage when stream_options.include_usage is set
+	if chunk.Usage.TotalTokens > 0 {
+		s.usage = convertUsage(chunk.Usage)
+	}
+
+	var parts []*ai.Part
+	for _, streamChoice := range chunk.Choices {
+		idx := int(streamChoice.Index)
+		first := idx == 0
+		acc := s.choices[idx]
+		if acc == nil {
+			acc = &choiceAccumulator{toolCalls: make(map[int]*toolCallAccumulator)}
+			s.choices[idx] = acc
+		}
+
+		delta := streamChoice.Delta
+		if streamChoice.FinishReason != "" {
+			acc.finishReason = streamChoice.FinishReason
+		}
+		if len(streamChoice.Logprobs.Content) > 0 {
+			acc.logprobs = append(acc.logprobs, convertLogprobs(streamChoice.Logprobs.Content)...)
+		}
+
+		// Reasoning models emit their reasoning separately from the answer
+		if reasoning := reasoningFromDelta(delta); reasoning != "" {
+			acc.reasoning.WriteString(reasoning)
+			if first {
+				parts = append(parts, newReasoningPart(reasoning))
+			}
+		}
+
+		if delta.Content != "" {
+			acc.text.WriteString(delta.Content)
+			if first {
+				parts = append(parts, ai.NewTextPart(delta.Content))
+			}
+		}
+
+		if audio, ok := audioFromDelta(delta); ok {
+			data, err := acc.audio.add(audio)
+			if err != nil {
+				return nil, err
+			}
+			if first && len(data) > 0 {
+				parts = append(parts, audioPart(openai.ChatCompletionAudio{ID: audio.ID, Data: audio.Data, Transcript: audio.Transcript}, s.audioContentType()))
+			}
+		}
+
+		if delta.Refusal != "" {
+			acc.refusal.WriteString(delta.Refusal)
+			if first {
+				parts = append(parts, newRefusalPart(delta.Refusal))
+			}
+		}
+
+		for _, toolCallDelta := range delta.ToolCalls {
+			toolCall, err := acc.addToolCall(int(toolCallDelta.Index), toolCallDelta.ID, toolCallDelta.Function.Name, toolCallDelta.Function.Arguments, maxArgBytes)
+			if err != nil {
+				return nil, err
+			}
+			// Report tool-call progress once the name is known
+			if s.PartialToolCalls && first && toolCall.name != "" {
+				parts = append(parts, partialToolRequestPart(toolCall))
+			}
+		}
+
+		// Older API versions stream a single legacy function_call instead of tool_calls
+		if fc := delta.FunctionCall; len(delta.ToolCalls) == 0 && (fc.Name != "" || fc.Arguments != "") {
+			toolCall, err := acc.addToolCall(0, "", fc.Name, fc.Arguments, maxArgBytes)
+			if err != nil {
+				return nil, err
+			}
+			if s.PartialToolCalls && first && toolCall.name != "" {
+				parts = append(parts, partialToolRequestPart(toolCall))
+			}
+		}
+	}
+	return parts, nil
+}
+
+// addToolCall accumulates a tool call delta and returns the call
+func (c *choiceAccumulator) addToolCall(index int, id, name, arguments string, maxArgBytes int) (*toolCallAccumulator, error) {
+	toolCall := c.toolCalls[index]
+	if toolCall == nil {
+		toolCall = &toolCallAccumulator{}
+		c.toolCalls[index] = toolCall
+	}
+	if id != "" {
+		toolCall.id = id
+	}
+	if name != "" {
+		toolCall.name = name
+	}
+	if arguments != "" {
+		if toolCall.arguments.Len()+len(arguments) > maxArgBytes {
+			return nil, fmt.Errorf("tool call '%s' arguments exceed %d bytes", toolCall.name, maxArgBytes)
+		}
+		toolCall.arguments.WriteString(arguments)
+	}
+	return toolCall, nil
+}
+
+// Usage returns the usage reported by the final chunk, which Azure only sends when
+// stream_options.include_usage is set; empty otherwise
+func (s *StreamAccumulator) Usage() *ai.GenerationUsage {
+	if s.usage == nil {
+		return &ai.GenerationUsage{}
+	}
+	return s.usage
+}
+
+// Message returns the assembled message of the first choice, or nil if none was streamed
+func (s *StreamAccumulator) Message() (*ai.Message, error) {
+	candidates, err := s.Candidates()
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+	return candidates[0].Message, nil
+}
+
+// Candidates returns every assembled choice in index order
+func (s *StreamAccumulator) Candidates() ([]*Candidate, error) {
+	return s.candidates(nil, false, false)
+}
+
+// candidates assembles the choices, passing their text through trim when set. With
+// emptyIsError a choice without any output is an error. With stopped the stream was cut
+// short, so the tool call an unfinished choice was still streaming is left out.
+func (s *StreamAccumulator) candidates(trim func(string) string, emptyIsError, stopped bool) ([]*Candidate, error) {
+	indices := make([]int, 0, len(s.choices))
+	for idx := range s.choices {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+
+	candidates := make([]*Candidate, 0, len(indices))
+	for _, idx := range indices {
+		acc := s.choices[idx]
+
+		var content []*ai.Part
+		if acc.reasoning.Len() > 0 {
+			content = append(content, newReasoningPart(acc.reasoning.String()))
+		}
+		text := acc.text.String()
+		if trim != nil {
+			text = trim(text)
+		}
+		if text != "" {
+			content = append(content, ai.NewTextPart(text))
+		}
+		if acc.refusal.Len() > 0 {
+			content = append(content, newRefusalPart(acc.refusal.String()))
+		}
+		if audio, ok := acc.audio.result(); ok {
+			content = append(content, audioPart(audio, s.audioContentType()))
+		}
+
+		toolCalls := acc.toolCalls
+		if stopped && acc.finishReason == "" {
+			toolCalls = withoutLastToolCall(toolCalls)
+		}
+		toolParts, err := convertToolCallsToParts(toolCalls)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert tool calls: %w", err)
+		}
+		content = append(content, toolParts...)
+
+		// A stream can end without any output, e.g. when the content filter blocks it at once
+		if emptyIsError && len(content) == 0 {
+			return nil, emptyResponseError(acc.finishReason)
+		}
+
+		message := &ai.Message{
+			Role:    ai.RoleModel,
+			Content: content,
+		}
+		setSystemFingerprint(message, s.systemFingerprint)
+		if s.serviceTier != "" {
+			if message.Metadata == nil {
+				message.Metadata = make(map[string]any)
+			}
+			message.Metadata["serviceTier"] = s.serviceTier
+		}
+		setLogprobs(message, acc.logprobs)
+
+		candidates = append(candidates, refusalCandidate(&Candidate{
+			Index:         idx,
+			Message:       message,
+			FinishReason:  convertFinishReason(acc.finishReason),
+			FinishMessage: finishMessage(acc.finishReason),
+		}, acc.refusal.String()))
+	}
+	return candidates, nil
+}
+
+// withoutLastToolCall returns the tool calls without the one of highest index. Calls are
+// streamed one after another, so only that one can be incomplete.
+func withoutLastToolCall(toolCalls map[int]*toolCallAccumulator) map[int]*toolCallAccumulator {
+	last := -1
+	for idx := range toolCalls {
+		last = max(last, idx)
+	}
+	complete := make(map[int]*toolCallAccumulator, len(toolCalls))
+	for idx, toolCall := range toolCalls {
+		if idx != last {
+			complete[idx] = toolCall
+		}
+	}
+	return complete
+}
+
+// audioContentType returns the MIME type for spoken output parts
+func (s *StreamAccumulator) audioContentType() string {
+	if s.AudioContentType == "" {
+		return "audio/wav"
+	}
+	return s.AudioContentType
+}
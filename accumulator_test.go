@@ -0,0 +1,145 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/openai/openai-go/v3"
+)
+
+// chunk converts a streamChunk-style map into an SDK chunk, as a caller would read it
+func chunk(t *testing.T, raw map[string]any) openai.ChatCompletionChunk {
+	t.Helper()
+	var c openai.ChatCompletionChunk
+	if err := cloneJSON(raw, &c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestStreamAccumulator(t *testing.T) {
+	// Choice 1 only appears in a stream with "n" > 1; it is accumulated but not returned by Add
+	second := streamChunk(map[string]any{"content": "Other answer."}, "stop")
+	second["choices"].([]any)[0].(map[string]any)["index"] = 1
+
+	usage := streamChunk(map[string]any{}, "")
+	usage["choices"] = []any{}
+	usage["usage"] = map[string]any{"prompt_tokens": 10, "completion_tokens": 6, "total_tokens": 16}
+
+	chunks := []map[string]any{
+		streamChunk(map[string]any{"role": "assistant", "content": "Checking "}, ""),
+		streamChunk(map[string]any{"content": "the weather."}, ""),
+		second,
+		streamChunk(map[string]any{"tool_calls": []any{map[string]any{
+			"index": 0, "id": "call_1", "type": "function",
+			"function": map[string]any{"name": "weather", "arguments": `{"city":`},
+		}}}, ""),
+		streamChunk(map[string]any{"tool_calls": []any{map[string]any{
+			"index": 0, "function": map[string]any{"arguments": `"Madrid"}`},
+		}}}, "tool_calls"),
+		usage,
+	}
+
+	var acc StreamAccumulator
+	var streamed []string
+	for _, raw := range chunks {
+		parts, err := acc.Add(chunk(t, raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, part := range parts {
+			if !part.IsText() {
+				t.Errorf("Add returned a %v part without PartialToolCalls", part.Kind)
+			}
+			streamed = append(streamed, part.Text)
+		}
+	}
+	if want := []string{"Checking ", "the weather."}; !reflect.DeepEqual(streamed, want) {
+		t.Errorf("Add returned %q, want %q", streamed, want)
+	}
+
+	msg, err := acc.Message()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Role != ai.RoleModel || msg.Text() != "Checking the weather." {
+		t.Errorf("Message() = %s %q", msg.Role, msg.Text())
+	}
+	var requests []*ai.ToolRequest
+	for _, part := range msg.Content {
+		if part.IsToolRequest() {
+			requests = append(requests, part.ToolRequest)
+		}
+	}
+	if len(requests) != 1 || requests[0].Ref != "call_1" || requests[0].Name != "weather" ||
+		!reflect.DeepEqual(requests[0].Input, map[string]any{"city": "Madrid"}) {
+		t.Errorf("tool requests = %+v, want weather(call_1) with the joined arguments", requests)
+	}
+
+	if got := acc.Usage(); got.InputTokens != 10 || got.OutputTokens != 6 || got.TotalTokens != 16 {
+		t.Errorf("Usage() = %+v, want 10/6/16", got)
+	}
+
+	candidates, err := acc.Candidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 2 {
+		t.Fatalf("got %d candidates, want 2", len(candidates))
+	}
+	if c := candidates[0]; c.Index != 0 || c.Message.Text() != "Checking the weather." || c.FinishReason != ai.FinishReasonStop || c.FinishMessage != "tool_calls" {
+		t.Errorf("candidates[0] = %+v", c)
+	}
+	if c := candidates[1]; c.Index != 1 || c.Message.Text() != "Other answer." || c.FinishReason != ai.FinishReasonStop {
+		t.Errorf("candidates[1] = %+v", c)
+	}
+}
+
+func TestStreamAccumulatorZeroValue(t *testing.T) {
+	var acc StreamAccumulator
+	msg, err := acc.Message()
+	if err != nil || msg != nil {
+		t.Errorf("Message() = %v, %v, want nil, nil before any chunk", msg, err)
+	}
+	if usage := acc.Usage(); usage == nil || usage.TotalTokens != 0 {
+		t.Errorf("Usage() = %+v, want empty usage", usage)
+	}
+}
+
+func TestStreamAccumulatorPartialToolCalls(t *testing.T) {
+	acc := StreamAccumulator{PartialToolCalls: true, MaxToolArgumentBytes: 16}
+	parts, err := acc.Add(chunk(t, streamChunk(map[string]any{"tool_calls": []any{map[string]any{
+		"index": 0, "id": "call_1", "type": "function",
+		"function": map[string]any{"name": "weather", "arguments": `{"city":`},
+	}}}, "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 1 || !parts[0].IsToolRequest() || parts[0].ToolRequest.Name != "weather" || parts[0].Metadata["partial"] != true {
+		t.Errorf("Add returned %+v, want one partial weather request", parts)
+	}
+
+	// The arguments now exceed MaxToolArgumentBytes
+	if _, err := acc.Add(chunk(t, streamChunk(map[string]any{"tool_calls": []any{map[string]any{
+		"index": 0, "function": map[string]any{"arguments": `"Madrid, Spain"}`},
+	}}}, ""))); err == nil {
+		t.Error("Add accepted arguments over MaxToolArgumentBytes")
+	}
+}
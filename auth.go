@@ -0,0 +1,91 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// defaultCredentialScopes are the token scopes requested from a credential when
+// CredentialScopes is not set
+var defaultCredentialScopes = []string{"https://cognitiveservices.azure.com/.default"}
+
+// credentialPipelineVersion identifies the plugin in the telemetry of credential pipelines
+const credentialPipelineVersion = "v1"
+
+// requestTokenKey is the context key for a per-request bearer token
+type requestTokenKey struct{}
+
+// WithRequestToken returns a context that makes the plugin authenticate calls made with it
+// using the given Entra ID access token instead of the plugin-level API key or credential.
+// Use it for On-Behalf-Of flows where each end user has their own token and Azure RBAC.
+func WithRequestToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, requestTokenKey{}, token)
+}
+
+// requestTokenFromContext returns the per-request bearer token, if any
+func requestTokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(requestTokenKey{}).(string)
+	return token, ok && token != ""
+}
+
+// tokenCredentialMiddleware authenticates calls with a bearer token from cred, as
+// azure.WithTokenCredential does. Calls carrying a per-request token skip cred entirely, so
+// On-Behalf-Of calls neither need a working app credential nor spend a token fetch on it.
+// Tokens are cached across calls by the shared bearer token policy.
+func tokenCredentialMiddleware(cred azcore.TokenCredential, scopes []string) option.Middleware {
+	bearer := runtime.NewBearerTokenPolicy(cred, scopes, nil)
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		if _, ok := requestTokenFromContext(req.Context()); ok {
+			return next(req) // requestTokenMiddleware sets the Authorization header
+		}
+
+		pipeline := runtime.NewPipeline(provider, credentialPipelineVersion, runtime.PipelineOptions{}, &policy.ClientOptions{
+			InsecureAllowCredentialWithHTTP: true, // Allow plain HTTP proxies
+			PerRetryPolicies:                []policy.Policy{bearer, nextPolicy(next)},
+		})
+		azReq, err := runtime.NewRequestFromRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		return pipeline.Do(azReq)
+	}
+}
+
+// nextPolicy ends an Azure pipeline by handing the request back to the OpenAI middleware chain
+type nextPolicy option.MiddlewareNext
+
+// Do sends the request on through the remaining middleware
+func (next nextPolicy) Do(req *policy.Request) (*http.Response, error) {
+	return next(req.Raw())
+}
+
+// requestTokenMiddleware overrides authentication with the per-request token from the context.
+// It must be registered after the plugin credential so it runs last and wins.
+func requestTokenMiddleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	if token, ok := requestTokenFromContext(req.Context()); ok {
+		req.Header.Del("Api-Key")
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return next(req)
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/firebase/genkit/go/ai"
+)
+
+// fakeCredential hands out a fixed token, or fails, and counts how often it was asked
+type fakeCredential struct {
+	token string
+	err   error
+	calls atomic.Int32
+}
+
+func (c *fakeCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	c.calls.Add(1)
+	if c.err != nil {
+		return azcore.AccessToken{}, c.err
+	}
+	return azcore.AccessToken{Token: c.token, ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestRequestTokenOverridesCredential(t *testing.T) {
+	tests := []struct {
+		name         string
+		credential   *fakeCredential
+		requestToken string
+		wantAuth     string
+		wantFetches  int32
+	}{
+		{
+			name:        "plugin credential",
+			credential:  &fakeCredential{token: "app-token"},
+			wantAuth:    "Bearer app-token",
+			wantFetches: 1,
+		},
+		{
+			name:         "request token skips the credential",
+			credential:   &fakeCredential{token: "app-token"},
+			requestToken: "user-token",
+			wantAuth:     "Bearer user-token",
+			wantFetches:  0,
+		},
+		{
+			name:         "request token works without a usable credential",
+			credential:   &fakeCredential{err: errors.New("no managed identity")},
+			requestToken: "user-token",
+			wantAuth:     "Bearer user-token",
+			wantFetches:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotAuth, gotAPIKey, gotPath string
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAuth = r.Header.Get("Authorization")
+				gotAPIKey = r.Header.Get("Api-Key")
+				gotPath = r.URL.Path
+				writeChatCompletion(w, "ok")
+			}))
+			t.Cleanup(server.Close)
+
+			a := &AzureAIFoundry{
+				Endpoint:   server.URL,
+				Credential: tt.credential,
+				HTTPClient: server.Client(),
+			}
+			a.Init(context.Background())
+
+			ctx := context.Background()
+			if tt.requestToken != "" {
+				ctx = WithRequestToken(ctx, tt.requestToken)
+			}
+			_, err := a.generateText(ctx, "gpt-4o", modelSettings{}, &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if gotAuth != tt.wantAuth {
+				t.Errorf("Authorization = %q, want %q", gotAuth, tt.wantAuth)
+			}
+			if gotAPIKey != "" {
+				t.Errorf("Api-Key = %q, want none", gotAPIKey)
+			}
+			if gotPath != "/openai/deployments/gpt-4o/chat/completions" {
+				t.Errorf("path = %q", gotPath)
+			}
+			if n := tt.credential.calls.Load(); n != tt.wantFetches {
+				t.Errorf("credential asked for %d tokens, want %d", n, tt.wantFetches)
+			}
+		})
+	}
+}
@@ -21,13 +21,27 @@ package azureaifoundry
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+	"unicode"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
@@ -37,10 +51,28 @@ import (
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/azure"
 	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/shared"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const provider = "azureaifoundry"
 
+// DefaultAPIVersion is the Azure OpenAI API version used when APIVersion is not set
+const DefaultAPIVersion = "2025-03-01-preview"
+
+// apiVersionPattern matches Azure OpenAI API versions such as "2024-02-01" or "2025-03-01-preview"
+var apiVersionPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(-preview)?$`)
+
+// ErrEmptyResponse is returned when EmptyResponseIsError is set and Azure returns a completion
+// with no text and no tool calls
+var ErrEmptyResponse = errors.New("azureaifoundry: empty response from model")
+
+// defaultEmbeddingBatchSize is the default number of documents per embeddings call
+const defaultEmbeddingBatchSize = 16
+
+// defaultMaxToolArgumentBytes is the default cap for a single streamed tool call's arguments
+const defaultMaxToolArgumentBytes = 1 << 20
+
 // fileReader wraps a bytes.Reader to provide a filename for multipart uploads
 type fileReader struct {
 	*bytes.Reader
@@ -56,20 +88,164 @@ func (f *fileReader) Name() string {
 type AzureAIFoundry struct {
 	Endpoint   string                 // Azure AI Foundry endpoint URL (required)
 	APIKey     string                 // API key for authentication (required if not using DefaultAzureCredential)
-	APIVersion string                 // Azure OpenAI API version (e.g., "2024-12-01-preview", "2024-02-01"). Defaults to "2024-12-01-preview" if not specified
+	APIVersion string                 // Azure OpenAI API version (e.g., "2024-12-01-preview", "2024-02-01"). Defaults to DefaultAPIVersion if not specified
 	Credential azcore.TokenCredential // Optional: Use Azure DefaultAzureCredential instead of API key
 
+	// CredentialScopes overrides the token scopes requested from Credential (or the default credential).
+	// Defaults to "https://cognitiveservices.azure.com/.default"; sovereign clouds need their own scope,
+	// e.g. "https://cognitiveservices.azure.us/.default" for Azure Government
+	CredentialScopes []string
+
+	// AutoToolSchemaRepair normalizes tool input schemas and sends tools in Azure strict mode when
+	// their schema allows it: objects get "additionalProperties": false, all properties become
+	// required and unsupported keywords such as "default" are stripped. Tools taking map inputs
+	// (a schema-valued additionalProperties) are sent non-strict with their keywords intact
+	AutoToolSchemaRepair bool
+
+	// MinifyWhitespace collapses redundant whitespace in text parts before sending to save prompt tokens.
+	// Fenced code blocks are left untouched.
+	MinifyWhitespace bool
+
+	// MergeSystemMessages sends several system messages as one, joined with newlines, for models
+	// that handle more than one poorly: "consecutive" merges adjacent ones and "all" merges every
+	// system message into the position of the first. Unset keeps them separate
+	MergeSystemMessages SystemMessageMerge
+
+	// ToolCallBudget caps the number of tool requests returned from a single response (0 = unlimited).
+	// Extra tool calls are dropped and the response message is annotated with "toolCallsTruncated".
+	ToolCallBudget int
+
+	// EmptyResponseIsError treats a successful completion with no content and no tool calls
+	// as an error (ErrEmptyResponse) so callers' retry logic can kick in
+	EmptyResponseIsError bool
+
+	// MaxToolArgumentBytes caps the accumulated arguments of a single streamed tool call.
+	// Streaming aborts with an error when exceeded. Defaults to 1 MiB if not specified
+	MaxToolArgumentBytes int
+
+	// RawResponse attaches the unmodified API response to response metadata under RawResponseKey,
+	// for fields the plugin does not map (e.g. prompt filter results)
+	RawResponse bool
+
+	// ValidateToolArguments checks tool requests against the input schemas of the request's tools and
+	// fails the generation with a *ToolArgumentsError when a call is undeclared or malformed
+	ValidateToolArguments bool
+
+	// StreamToolCalls forwards tool-call progress to the streaming callback: a chunk with a partial
+	// tool request part is sent when a call's name first appears and as its arguments accumulate.
+	// Partial parts carry the raw argument text in Metadata["arguments"] and Metadata["partial"] = true
+	StreamToolCalls bool
+
+	// DatasetSink, if set, receives every successful chat (request, response) pair for dataset building
+	DatasetSink DatasetSink
+	// DatasetRedactor, if set, is applied to every text part of a record before it reaches DatasetSink (e.g. PII scrubbing)
+	DatasetRedactor func(text string) string
+
+	// ModelCapabilities registers the capabilities of deployments by name (case-insensitive). DefineModel
+	// and DefineCommonModels use an entry instead of inferring capabilities from the name, which
+	// custom and fine-tuned deployment names often defeat. An explicit ModelInfo still wins.
+	ModelCapabilities map[string]ai.ModelSupports
+
+	// EmbeddingBatchSize is the number of documents sent per embeddings call. Defaults to 16 if not specified
+	EmbeddingBatchSize int
+	// EmbeddingConcurrency is the number of embedding batches run in parallel. Defaults to 1 (sequential)
+	EmbeddingConcurrency int
+
+	// RequestTimeout bounds each chat and embedding call, including retries and the whole stream
+	// (0 = bounded only by the caller's context). The "timeout" config key overrides it per request.
+	// Calls that run out of time fail with ErrTimeout
+	RequestTimeout time.Duration
+
+	// MaxRetries is the number of retries for chat completion and embedding calls that fail with
+	// HTTP 429 or 500/502/503/504 (0 = rely on the OpenAI SDK's built-in retries)
+	MaxRetries int
+	// RetryBaseDelay is the initial backoff delay, doubled on each retry. Defaults to 500ms if not specified
+	RetryBaseDelay time.Duration
+	// RetryJitter is the maximum random delay added to each backoff
+	RetryJitter time.Duration
+
+	// InlineImageURLs makes the plugin download remote image URLs and send them inline as base64,
+	// instead of letting Azure fetch them
+	InlineImageURLs bool
+	// MaxInlineImageBytes limits the size of images downloaded by InlineImageURLs. Defaults to 20 MiB if not specified
+	MaxInlineImageBytes int64
+
+	// TrimResult trims trailing whitespace and leaked stop sequences from the final response text
+	TrimResult bool
+
+	// StateStore maps logical conversation IDs (config key "conversationId") to Azure response IDs
+	// so Responses API models can continue conversations server-side
+	StateStore StateStore
+
+	// OpenAICompatible points the plugin at a plain OpenAI-compatible server (e.g. vLLM or Ollama
+	// for local testing) instead of Azure: Endpoint is used verbatim as the base URL, APIKey is
+	// sent as a bearer token and APIVersion and Azure credentials are ignored
+	OpenAICompatible bool
+
+	// HTTPClient, if set, is used for all requests made by the plugin (e.g. for proxies,
+	// custom root CAs or transport-level timeouts)
+	HTTPClient *http.Client
+
+	// Headers are static HTTP headers sent with every Azure call (e.g. a team header for cost
+	// attribution). Per-request headers come from the "headers" config key or WithRequestHeaders
+	Headers map[string]string
+
+	// ExtraOptions are raw OpenAI SDK request options (custom headers, beta flags, ...) appended
+	// after the plugin's own options in Init, so they can override its defaults
+	ExtraOptions []option.RequestOption
+
+	// Logger, if set, receives a debug-level record for every model and embedding call with the
+	// model name, token counts, finish reason and latency
+	Logger *slog.Logger
+	// LogContent adds request messages and response text to Logger records (redacted by default)
+	LogContent bool
+
+	// Tracer, if set, starts a client span for every model and embedding call with gen-ai
+	// semantic-convention attributes (model, token usage, finish reason) and recorded errors
+	Tracer trace.Tracer
+
+	// User is the default end-user identifier sent in the "user" field of chat, embedding and
+	// image requests for abuse monitoring (use a stable hash, not personal data).
+	// The "user" config key overrides it per request
+	User string
+
+	// MaxInputTokens rejects chat requests whose estimated prompt exceeds it with ErrInputTooLarge
+	// before calling Azure (0 = no limit). The estimate is approximate (about four characters per token).
+	MaxInputTokens int
+
+	// RequestsPerMinute throttles chat and embedding calls client-side to stay under the
+	// deployment's RPM quota instead of relying on 429 responses (0 = unlimited)
+	RequestsPerMinute int
+	// TokensPerMinute throttles calls client-side by estimated tokens (prompt plus maximum output)
+	// to stay under the TPM quota; estimates are corrected with actual usage (0 = unlimited)
+	TokensPerMinute int
+
+	// FailoverEndpoints are secondary resources, in priority order, serving the same deployments
+	// as Endpoint (e.g. in other regions). A call failing with HTTP 429, 5xx or a network error
+	// is resent to the next endpoint, and the failing one is skipped for FailoverCooldown
+	FailoverEndpoints []FailoverEndpoint
+	// FailoverCooldown is how long a failing endpoint is skipped. Defaults to 30 seconds if not specified
+	FailoverCooldown time.Duration
+
+	// OpenAIClient, if set, replaces the SDK client for chat completion and embedding calls,
+	// e.g. with a fake in unit tests. Other APIs still use the client built in Init
+	OpenAIClient OpenAIClient
+
 	mu      sync.Mutex // Mutex to control access
 	client  openai.Client
-	initted bool // Whether the plugin has been initialized
+	api     OpenAIClient            // Chat and embeddings client: OpenAIClient or the SDK client
+	limiter *rateLimiter            // Client-side quota throttling, nil when disabled
+	initted bool                    // Whether the plugin has been initialized
+	models  map[string]ai.ModelFunc // Wrapped model functions by registered name
 }
 
 // ModelDefinition represents a model with its name and type.
 type ModelDefinition struct {
 	Name          string // Model deployment name in Azure AI Foundry
-	Type          string // Type: "chat", "text"
-	MaxTokens     int32  // Maximum tokens the model can handle (optional)
+	Type          string // Type: "chat", or "text" for legacy completions deployments (e.g. gpt-35-turbo-instruct)
+	MaxTokens     int32  // Maximum output tokens; requests asking for more are clamped to it (optional)
 	SupportsMedia bool   // Whether the model supports media (images, audio) (optional)
+	API           string // API surface for chat models: "chat" (default) or "responses" (optional)
 }
 
 // Name returns the provider name.
@@ -90,42 +266,136 @@ func (a *AzureAIFoundry) Init(ctx context.Context) []api.Action {
 	if a.Endpoint == "" {
 		panic("azureaifoundry: Endpoint is required")
 	}
+	if err := a.MergeSystemMessages.validate(); err != nil {
+		panic("azureaifoundry: " + err.Error())
+	}
+
+	// Create client options for Azure or, for local testing, a plain OpenAI-compatible server
+	var opts []option.RequestOption
+	if a.OpenAICompatible {
+		opts = a.openAICompatibleOptions()
+	} else {
+		opts = a.azureOptions()
+	}
+
+	if a.HTTPClient != nil {
+		opts = append(opts, option.WithHTTPClient(a.HTTPClient))
+	}
+
+	// Plugin-level retries replace the SDK's own so attempts don't multiply
+	if a.MaxRetries > 0 {
+		opts = append(opts, option.WithMaxRetries(0))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(a.Headers)) {
+		opts = append(opts, option.WithHeader(key, a.Headers[key]))
+	}
+
+	// Per-request headers (see WithRequestHeaders) are applied on top of the static ones
+	opts = append(opts, option.WithMiddleware(requestHeadersMiddleware))
+
+	// Per-request tokens (see WithRequestToken) take precedence over the plugin credential
+	opts = append(opts, option.WithMiddleware(requestTokenMiddleware))
+
+	// Failover runs last so it can swap the endpoint and key of the fully prepared request
+	if len(a.FailoverEndpoints) > 0 {
+		if a.OpenAICompatible {
+			panic("azureaifoundry: FailoverEndpoints cannot be used with OpenAICompatible")
+		}
+		primary, _ := normalizeEndpoint(a.Endpoint) // Already validated by azureOptions
+		f, err := newFailover(primary, a.FailoverEndpoints, a.FailoverCooldown)
+		if err != nil {
+			panic("azureaifoundry: failover: " + err.Error())
+		}
+		opts = append(opts, option.WithMiddleware(f.middleware))
+	}
+
+	// Caller-supplied options go last so they take precedence
+	opts = append(opts, a.ExtraOptions...)
+
+	a.client = openai.NewClient(opts...)
+	a.api = a.OpenAIClient
+	if a.api == nil {
+		a.api = sdkClient{client: &a.client}
+	}
+	a.limiter = newRateLimiter(a.RequestsPerMinute, a.TokensPerMinute)
+	a.initted = true
+
+	return []api.Action{}
+}
+
+// checkReady returns ErrNotInitialized unless Init has set up a client. Model and embedder
+// functions outlive DefineModel's own check, so calls check again before using the client.
+func (a *AzureAIFoundry) checkReady() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.initted || a.api == nil {
+		return ErrNotInitialized
+	}
+	return nil
+}
+
+// azureOptions returns the client options for an Azure OpenAI resource: deployment-based
+// URLs, the api-version query parameter and Azure authentication
+func (a *AzureAIFoundry) azureOptions() []option.RequestOption {
+	endpoint, err := normalizeEndpoint(a.Endpoint)
+	if err != nil {
+		panic("azureaifoundry: " + err.Error())
+	}
 
 	// Set default API version if not specified
 	apiVersion := a.APIVersion
 	if apiVersion == "" {
-		apiVersion = "2025-03-01-preview"
+		apiVersion = DefaultAPIVersion
+	}
+	if !apiVersionPattern.MatchString(apiVersion) {
+		panic(fmt.Sprintf("azureaifoundry: malformed APIVersion %q (expected YYYY-MM-DD or YYYY-MM-DD-preview)", apiVersion))
 	}
-
-	// Create client options using Azure-specific configuration
-	var opts []option.RequestOption
 
 	// Use azure.WithEndpoint which properly handles Azure OpenAI deployment-based URLs
-	opts = append(opts, azure.WithEndpoint(a.Endpoint, apiVersion))
+	opts := []option.RequestOption{azure.WithEndpoint(endpoint, apiVersion)}
+
+	scopes := defaultCredentialScopes
+	if len(a.CredentialScopes) > 0 {
+		scopes = a.CredentialScopes
+	}
 
 	if a.APIKey != "" {
 		// Use API key authentication
 		opts = append(opts, azure.WithAPIKey(a.APIKey))
 	} else if a.Credential != nil {
 		// Use token credential
-		opts = append(opts, azure.WithTokenCredential(a.Credential))
+		opts = append(opts, option.WithMiddleware(tokenCredentialMiddleware(a.Credential, scopes)))
 	} else {
 		// Try default Azure credential
 		cred, err := azidentity.NewDefaultAzureCredential(nil)
 		if err != nil {
 			panic(fmt.Sprintf("azureaifoundry: failed to create default credential: %v", err))
 		}
-		opts = append(opts, azure.WithTokenCredential(cred))
+		opts = append(opts, option.WithMiddleware(tokenCredentialMiddleware(cred, scopes)))
 	}
 
-	a.client = openai.NewClient(opts...)
-	a.initted = true
+	return opts
+}
 
-	return []api.Action{}
+// openAICompatibleOptions returns the client options for a plain OpenAI-compatible server:
+// the endpoint is used verbatim as the base URL and APIKey, if set, is sent as a bearer token
+func (a *AzureAIFoundry) openAICompatibleOptions() []option.RequestOption {
+	baseURL, err := url.Parse(a.Endpoint)
+	if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+		panic(fmt.Sprintf("azureaifoundry: invalid Endpoint %q: must be an absolute URL such as http://localhost:8000/v1/", a.Endpoint))
+	}
+
+	opts := []option.RequestOption{option.WithBaseURL(a.Endpoint)}
+	if a.APIKey != "" {
+		opts = append(opts, option.WithAPIKey(a.APIKey))
+	}
+	return opts
 }
 
-// DefineModel defines a model in the registry.
-func (a *AzureAIFoundry) DefineModel(g *genkit.Genkit, model ModelDefinition, info *ai.ModelInfo) ai.Model {
+// DefineModel defines a model in the registry. Options can attach default generation
+// parameters (WithDefaults) or register the deployment under another name (WithName).
+func (a *AzureAIFoundry) DefineModel(g *genkit.Genkit, model ModelDefinition, info *ai.ModelInfo, opts ...ModelOption) ai.Model {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -133,30 +403,115 @@ func (a *AzureAIFoundry) DefineModel(g *genkit.Genkit, model ModelDefinition, in
 		panic("azureaifoundry: Init not called")
 	}
 
+	var options modelOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	name := cmp.Or(options.name, model.Name)
+
 	// Auto-detect model capabilities if not provided
 	if info == nil {
 		info = a.inferModelCapabilities(model.Name, model.SupportsMedia)
+		if model.Type == "text" {
+			// Legacy completions take a plain prompt: no tools or media
+			textInfo := *info
+			supports := *info.Supports
+			supports.Tools = false
+			supports.ToolChoice = false
+			supports.Media = false
+			textInfo.Supports = &supports
+			info = &textInfo
+		}
+	}
+
+	// Settings of this definition, kept with its model function so aliases of one
+	// deployment don't share them
+	settings := modelSettings{api: model.API, maxTokens: int64(model.MaxTokens)}
+	if model.Type == "text" && settings.api == "" {
+		settings.api = apiCompletions
 	}
 
 	// Create model metadata
 	meta := &ai.ModelOptions{
-		Label:    provider + "-" + model.Name,
+		Label:    provider + "-" + name,
 		Supports: info.Supports,
 		Versions: info.Versions,
 	}
 
-	// Create the model function
-	return genkit.DefineModel(g, api.NewName(provider, model.Name), meta, func(
+	// Create the model function, kept so GenerateStream and GenerateWithTools call it too
+	fn := a.modelFunc(model.Name, options.defaults, settings)
+	if a.models == nil {
+		a.models = make(map[string]ai.ModelFunc)
+	}
+	a.models[name] = fn
+	return genkit.DefineModel(g, api.NewName(provider, name), meta, fn)
+}
+
+// modelFunc returns the function serving a deployment: it applies the model's default config,
+// then generates with its settings inside the Tracer span and Logger record of the call
+func (a *AzureAIFoundry) modelFunc(deployment string, defaults map[string]interface{}, settings modelSettings) ai.ModelFunc {
+	return func(
 		ctx context.Context,
 		input *ai.ModelRequest,
 		cb func(context.Context, *ai.ModelResponseChunk) error,
 	) (*ai.ModelResponse, error) {
-		return a.generateText(ctx, model.Name, input, cb)
-	})
+		input = applyConfigDefaults(input, defaults)
+		ctx, span := a.startSpan(ctx, "chat", deployment)
+		start := time.Now()
+		resp, err := a.generateText(ctx, deployment, settings, input, cb)
+		a.logGeneration(ctx, deployment, input, resp, err, time.Since(start))
+		endGenerationSpan(span, resp, err)
+		return resp, err
+	}
+}
+
+// lookupModel returns the function of the model registered under name with DefineModel, or
+// one serving the deployment of that name without defaults when no such model is defined
+func (a *AzureAIFoundry) lookupModel(name string) ai.ModelFunc {
+	a.mu.Lock()
+	fn, ok := a.models[name]
+	a.mu.Unlock()
+	if ok {
+		return fn
+	}
+	return a.modelFunc(name, nil, modelSettings{})
+}
+
+// EmbedderOptions configures how an embedder splits and parallelizes large document sets
+type EmbedderOptions struct {
+	BatchSize   int  // Documents sent per embeddings call. Defaults to the plugin's EmbeddingBatchSize
+	Concurrency int  // Maximum batches in flight at once. Defaults to the plugin's EmbeddingConcurrency
+	Base64      bool // Request base64-encoded vectors, roughly halving response size; decoded to []float32
+
+	// Dimensions is the embedding size reported to Genkit, so vector stores can check it up
+	// front. It defaults to the native size of known models. For text-embedding-3 models a
+	// smaller value is also sent as the "dimensions" parameter unless a request sets its own.
+	Dimensions int
+	Label      string               // Display name reported to Genkit. Defaults to "azureaifoundry-<model>"
+	Supports   *ai.EmbedderSupports // Input types and multilingual support reported to Genkit
+
+	// Progress, if set, is called after each batch completes. Calls are serialized, so the
+	// callback needs no locking of its own. It should return quickly.
+	Progress func(ctx context.Context, progress EmbedProgress)
+}
+
+// EmbedProgress reports how far a batched embedding request has got
+type EmbedProgress struct {
+	Done      int           // Documents embedded so far
+	Total     int           // Non-empty documents in the request
+	Batches   int           // Batches completed so far
+	Remaining time.Duration // Estimated time left, extrapolated from the elapsed time per document
 }
 
 // DefineEmbedder defines an embedder in the registry.
 func (a *AzureAIFoundry) DefineEmbedder(g *genkit.Genkit, modelName string) ai.Embedder {
+	return a.DefineEmbedderWithOptions(g, modelName, nil)
+}
+
+// DefineEmbedderWithOptions defines an embedder in the registry with its own batching and
+// concurrency limits and metadata. Batches run through a fixed-size worker pool, results keep
+// input order, and the first failing batch cancels the others.
+func (a *AzureAIFoundry) DefineEmbedderWithOptions(g *genkit.Genkit, modelName string, opts *EmbedderOptions) ai.Embedder {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -164,14 +519,146 @@ func (a *AzureAIFoundry) DefineEmbedder(g *genkit.Genkit, modelName string) ai.E
 		panic("azureaifoundry: Init not called")
 	}
 
-	return genkit.DefineEmbedder(g, api.NewName(provider, modelName), nil, func(
+	meta, err := embedderInfo(modelName, opts)
+	if err != nil {
+		panic(fmt.Sprintf("azureaifoundry: %v", err))
+	}
+
+	return genkit.DefineEmbedder(g, api.NewName(provider, modelName), meta, func(
 		ctx context.Context,
 		req *ai.EmbedRequest,
 	) (*ai.EmbedResponse, error) {
-		return a.embed(ctx, modelName, req)
+		ctx, span := a.startSpan(ctx, "embeddings", modelName)
+		start := time.Now()
+		resp, err := a.embed(ctx, modelName, req, opts)
+		a.logEmbedding(ctx, modelName, req, err, time.Since(start))
+		endEmbeddingSpan(span, req, err)
+		return resp, err
+	})
+}
+
+// DefineImageModel defines an image generation model (DALL-E, gpt-image) in the registry.
+// Generated images are returned as media parts: a URL, or a base64 data URI when the
+// "response_format" config is "b64_json".
+func (a *AzureAIFoundry) DefineImageModel(g *genkit.Genkit, modelName string) ai.Model {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.initted {
+		panic("azureaifoundry: Init not called")
+	}
+
+	meta := &ai.ModelOptions{
+		Label: provider + "-" + modelName,
+		Supports: &ai.ModelSupports{
+			Output: []string{"media"},
+		},
+	}
+
+	return genkit.DefineModel(g, api.NewName(provider, modelName), meta, func(
+		ctx context.Context,
+		input *ai.ModelRequest,
+		cb func(context.Context, *ai.ModelResponseChunk) error,
+	) (*ai.ModelResponse, error) {
+		return a.generateImageMedia(ctx, modelName, input)
 	})
 }
 
+// DefineTTSModel defines a text-to-speech model (tts-1, tts-1-hd, gpt-4o-mini-tts) in the registry.
+// The synthesized speech is returned as a media part holding a base64 data URI. When streaming,
+// audio bytes are forwarded to the callback as they are received.
+func (a *AzureAIFoundry) DefineTTSModel(g *genkit.Genkit, modelName string) ai.Model {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.initted {
+		panic("azureaifoundry: Init not called")
+	}
+
+	meta := &ai.ModelOptions{
+		Label: provider + "-" + modelName,
+		Supports: &ai.ModelSupports{
+			Output: []string{"media"},
+		},
+	}
+
+	return genkit.DefineModel(g, api.NewName(provider, modelName), meta, func(
+		ctx context.Context,
+		input *ai.ModelRequest,
+		cb func(context.Context, *ai.ModelResponseChunk) error,
+	) (*ai.ModelResponse, error) {
+		return a.generateSpeechMedia(ctx, modelName, input, cb)
+	})
+}
+
+// speechChunkSize is the size of the audio chunks sent to the streaming callback
+const speechChunkSize = 32 << 10
+
+// generateSpeechMedia handles text-to-speech for models defined with DefineTTSModel
+func (a *AzureAIFoundry) generateSpeechMedia(ctx context.Context, modelName string, input *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	req := speechRequestFromInput(input)
+	contentType, err := speechContentType(req.ResponseFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config for model '%s': %w", modelName, err)
+	}
+
+	body, err := a.openSpeech(ctx, modelName, req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = body.Close()
+	}()
+
+	var audio bytes.Buffer
+	buf := make([]byte, speechChunkSize)
+	for {
+		n, readErr := io.ReadFull(body, buf)
+		if n > 0 {
+			audio.Write(buf[:n])
+			if cb != nil {
+				chunk := &ai.ModelResponseChunk{
+					Content: []*ai.Part{
+						ai.NewMediaPart(contentType, "data:"+contentType+";base64,"+base64.StdEncoding.EncodeToString(buf[:n])),
+					},
+				}
+				if err := cb(ctx, chunk); err != nil {
+					return nil, fmt.Errorf("streaming callback error: %w", err)
+				}
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read audio data: %w", readErr)
+		}
+	}
+
+	return &ai.ModelResponse{
+		Message: &ai.Message{
+			Role: ai.RoleModel,
+			Content: []*ai.Part{
+				ai.NewMediaPart(contentType, "data:"+contentType+";base64,"+base64.StdEncoding.EncodeToString(audio.Bytes())),
+			},
+		},
+		FinishReason: ai.FinishReasonStop,
+	}, nil
+}
+
+// speechContentType returns the MIME type of a text-to-speech output format
+func speechContentType(format string) (string, error) {
+	switch format {
+	case "mp3":
+		return "audio/mpeg", nil
+	case "opus", "aac", "flac", "wav":
+		return "audio/" + format, nil
+	case "pcm":
+		return "audio/pcm", nil
+	}
+	return "", fmt.Errorf("response_format must be one of mp3, opus, aac, flac, wav, pcm, got %q", format)
+}
+
 // ImageGenerationRequest represents a request to generate images
 type ImageGenerationRequest struct {
 	Prompt         string // The text prompt to generate images from
@@ -180,6 +667,7 @@ type ImageGenerationRequest struct {
 	Quality        string // Quality: "standard" or "hd" (DALL-E 3 only)
 	Style          string // Style: "vivid" or "natural" (DALL-E 3 only)
 	ResponseFormat string // Format: "url" or "b64_json"
+	User           string // End-user identifier for abuse monitoring (defaults to the plugin's User)
 }
 
 // ImageGenerationResponse represents the response from image generation
@@ -200,7 +688,7 @@ func (a *AzureAIFoundry) generateImagesInternal(ctx context.Context, modelName s
 	a.mu.Lock()
 	if !a.initted {
 		a.mu.Unlock()
-		return nil, fmt.Errorf("azureaifoundry: client not initialized")
+		return nil, ErrNotInitialized
 	}
 	client := a.client
 	a.mu.Unlock()
@@ -226,11 +714,14 @@ func (a *AzureAIFoundry) generateImagesInternal(ctx context.Context, modelName s
 	if req.ResponseFormat != "" {
 		params.ResponseFormat = openai.ImageGenerateParamsResponseFormat(req.ResponseFormat)
 	}
+	if user := cmp.Or(req.User, a.User); user != "" {
+		params.User = openai.String(user)
+	}
 
 	// Generate images
 	resp, err := client.Images.Generate(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("image generation failed: %w", err)
+		return nil, fmt.Errorf("image generation failed: %w", classifyError(err))
 	}
 
 	// Convert response
@@ -263,10 +754,31 @@ type TTSResponse struct {
 
 // generateSpeechInternal converts text to speech using TTS models
 func (a *AzureAIFoundry) generateSpeechInternal(ctx context.Context, modelName string, req *TTSRequest) (*TTSResponse, error) {
+	body, err := a.openSpeech(ctx, modelName, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read all audio data from the response body
+	audioData, err := io.ReadAll(body)
+	if closeErr := body.Close(); closeErr != nil {
+		return nil, fmt.Errorf("failed to close response body: %w", closeErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio data: %w", err)
+	}
+
+	return &TTSResponse{
+		Audio: audioData,
+	}, nil
+}
+
+// openSpeech starts a text-to-speech call and returns the audio body, which the caller must close
+func (a *AzureAIFoundry) openSpeech(ctx context.Context, modelName string, req *TTSRequest) (io.ReadCloser, error) {
 	a.mu.Lock()
 	if !a.initted {
 		a.mu.Unlock()
-		return nil, fmt.Errorf("azureaifoundry: client not initialized")
+		return nil, ErrNotInitialized
 	}
 	client := a.client
 	a.mu.Unlock()
@@ -288,27 +800,16 @@ func (a *AzureAIFoundry) generateSpeechInternal(ctx context.Context, modelName s
 	// Generate speech
 	resp, err := client.Audio.Speech.New(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("speech generation failed: %w", err)
-	}
-
-	// Read all audio data from the response body
-	audioData, err := io.ReadAll(resp.Body)
-	if closeErr := resp.Body.Close(); closeErr != nil {
-		return nil, fmt.Errorf("failed to close response body: %w", closeErr)
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to read audio data: %w", err)
+		return nil, fmt.Errorf("speech generation failed: %w", classifyError(err))
 	}
-
-	return &TTSResponse{
-		Audio: audioData,
-	}, nil
+	return resp.Body, nil
 }
 
 // STTRequest represents a speech-to-text request
 type STTRequest struct {
 	Audio          []byte  // The audio file content
 	Filename       string  // Filename with extension (e.g., "audio.mp3", "audio.wav") - required for format detection
+	ContentType    string  // MIME type of the audio (e.g., "audio/wav"), used to derive Filename when it is empty
 	Language       string  // Language code (e.g., "en", "es")
 	Prompt         string  // Optional text to guide the model's style
 	ResponseFormat string  // Format: "json", "text", "srt", "verbose_json", "vtt"
@@ -317,9 +818,69 @@ type STTRequest struct {
 
 // STTResponse represents the speech-to-text response
 type STTResponse struct {
-	Text     string  // Transcribed text
-	Language string  // Detected language
-	Duration float64 // Duration in seconds
+	Text     string       // Transcribed text
+	Language string       // Detected language
+	Duration float64      // Duration in seconds
+	Segments []STTSegment // Timestamped segments (ResponseFormat "verbose_json" only)
+}
+
+// STTSegment is a timestamped segment of a transcription
+type STTSegment struct {
+	Start float64 // Start time in seconds
+	End   float64 // End time in seconds
+	Text  string  // Segment text
+}
+
+// Transcribe converts audio to text using a Whisper or gpt-4o transcription deployment
+func (a *AzureAIFoundry) Transcribe(ctx context.Context, modelName string, req *STTRequest) (*STTResponse, error) {
+	return a.transcribeAudioInternal(ctx, modelName, req)
+}
+
+// TranscribeStream transcribes audio and calls cb with partial text as it arrives.
+// Streaming is supported by the gpt-4o transcription models, not by whisper-1.
+func (a *AzureAIFoundry) TranscribeStream(ctx context.Context, modelName string, req *STTRequest, cb func(ctx context.Context, delta string) error) (*STTResponse, error) {
+	a.mu.Lock()
+	if !a.initted {
+		a.mu.Unlock()
+		return nil, ErrNotInitialized
+	}
+	client := a.client
+	a.mu.Unlock()
+
+	stream := client.Audio.Transcriptions.NewStreaming(ctx, transcriptionParams(modelName, req))
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	var text strings.Builder
+	var final string
+	for stream.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		event := stream.Current()
+		switch event.Type {
+		case "transcript.text.delta":
+			text.WriteString(event.Delta)
+			if err := cb(ctx, event.Delta); err != nil {
+				return nil, fmt.Errorf("streaming callback error: %w", err)
+			}
+		case "transcript.text.done":
+			final = event.Text
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("audio transcription failed: %w", classifyError(err))
+	}
+
+	if final == "" {
+		final = text.String()
+	}
+	return &STTResponse{
+		Text:     final,
+		Language: req.Language,
+	}, nil
 }
 
 // transcribeAudioInternal transcribes audio to text using Whisper models
@@ -327,15 +888,42 @@ func (a *AzureAIFoundry) transcribeAudioInternal(ctx context.Context, modelName
 	a.mu.Lock()
 	if !a.initted {
 		a.mu.Unlock()
-		return nil, fmt.Errorf("azureaifoundry: client not initialized")
+		return nil, ErrNotInitialized
 	}
 	client := a.client
 	a.mu.Unlock()
 
-	// Determine filename - use provided filename or default based on format
+	params := transcriptionParams(modelName, req)
+
+	// Transcribe audio
+	resp, err := client.Audio.Transcriptions.New(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("audio transcription failed: %w", classifyError(err))
+	}
+
+	var segments []STTSegment
+	for _, seg := range resp.Segments {
+		segments = append(segments, STTSegment{
+			Start: seg.Start,
+			End:   seg.End,
+			Text:  seg.Text,
+		})
+	}
+
+	return &STTResponse{
+		Text:     resp.Text,
+		Language: resp.Language,
+		Duration: resp.Duration,
+		Segments: segments,
+	}, nil
+}
+
+// transcriptionParams builds transcription parameters from a speech-to-text request
+func transcriptionParams(modelName string, req *STTRequest) openai.AudioTranscriptionNewParams {
+	// Determine filename - use provided filename or derive it from the content type
 	filename := req.Filename
 	if filename == "" {
-		filename = "audio.mp3" // Default to mp3 if not specified
+		filename = audioFilename(req.ContentType)
 	}
 
 	// Create a named reader for the file upload
@@ -364,37 +952,66 @@ func (a *AzureAIFoundry) transcribeAudioInternal(ctx context.Context, modelName
 	if req.Temperature > 0 {
 		params.Temperature = openai.Float(req.Temperature)
 	}
-
-	// Transcribe audio
-	resp, err := client.Audio.Transcriptions.New(ctx, params)
-	if err != nil {
-		return nil, fmt.Errorf("audio transcription failed: %w", err)
+	if req.ResponseFormat == "verbose_json" {
+		params.TimestampGranularities = []string{"segment"}
 	}
 
-	return &STTResponse{
-		Text:     resp.Text,
-		Language: resp.Language,
-		Duration: resp.Duration,
-	}, nil
+	return params
+}
+
+// audioFilename returns an upload filename whose extension matches an audio MIME type
+func audioFilename(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "wav"):
+		return "audio.wav"
+	case strings.Contains(contentType, "opus"):
+		return "audio.opus"
+	case strings.Contains(contentType, "ogg"):
+		return "audio.ogg"
+	case strings.Contains(contentType, "flac"):
+		return "audio.flac"
+	case strings.Contains(contentType, "webm"):
+		return "audio.webm"
+	case strings.Contains(contentType, "mp4"), strings.Contains(contentType, "m4a"):
+		return "audio.m4a"
+	default:
+		return "audio.mp3" // Default to mp3 if not specified
+	}
 }
 
 // inferModelCapabilities infers model capabilities based on model info.
 func (a *AzureAIFoundry) inferModelCapabilities(modelName string, supportsMedia bool) *ai.ModelInfo {
-	// Detect tool support based on model name
-	supportsTools := strings.Contains(strings.ToLower(modelName), "gpt")
+	// Registered capabilities take precedence over any inference
+	if supports, ok := a.registeredCapabilities(modelName); ok {
+		return &ai.ModelInfo{Label: modelName, Supports: &supports}
+	}
+
+	// Look up known model families; fall back to assuming GPT-named deployments support tools
+	supportsTools := strings.Contains(normalizeModelName(modelName), "gpt")
+	supportsSystemRole := true
+	if family, ok := lookupModelFamily(modelName); ok {
+		supportsTools = family.tools
+		supportsSystemRole = family.systemRole
+	}
+
 	return &ai.ModelInfo{
 		Label: modelName,
 		Supports: &ai.ModelSupports{
 			Multiturn:  true,
 			Tools:      supportsTools,
-			SystemRole: true,
+			ToolChoice: supportsTools,
+			SystemRole: supportsSystemRole,
 			Media:      supportsMedia,
 		},
 	}
 }
 
 // generateText handles text generation using Azure OpenAI
-func (a *AzureAIFoundry) generateText(ctx context.Context, modelName string, input *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+func (a *AzureAIFoundry) generateText(ctx context.Context, modelName string, model modelSettings, input *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	if err := a.checkReady(); err != nil {
+		return nil, err
+	}
+	input = withConfigMap(input)
 	modelLower := strings.ToLower(modelName)
 
 	// Handle image generation models (DALL-E)
@@ -409,65 +1026,147 @@ func (a *AzureAIFoundry) generateText(ctx context.Context, modelName string, inp
 
 	// Handle speech-to-text models (Whisper, transcribe)
 	if strings.Contains(modelLower, "whisper") || strings.Contains(modelLower, "transcribe") {
-		return a.transcribeAudioFromRequest(ctx, modelName, input)
-	}
-
-	// Default: standard chat completion
-	// Build chat completion parameters
-	params := a.buildChatCompletionParams(input, modelName)
-
-	// Handle streaming vs non-streaming
-	if cb != nil {
-		return a.generateTextStream(ctx, params, input, cb)
+		return a.transcribeAudioFromRequest(ctx, modelName, input, cb)
 	}
-	return a.generateTextSync(ctx, params, input)
-}
 
-// generateImages handles image generation through Genkit's Generate interface
-func (a *AzureAIFoundry) generateImages(ctx context.Context, modelName string, input *ai.ModelRequest) (*ai.ModelResponse, error) {
-	// Extract prompt from messages
-	var prompt string
-	for _, msg := range input.Messages {
-		for _, part := range msg.Content {
-			if part.IsText() {
-				prompt += part.Text
-			}
+	// Download remote images client-side when required by policy
+	if a.InlineImageURLs {
+		messages, err := a.inlineImageURLs(ctx, input.Messages)
+		if err != nil {
+			return nil, err
 		}
+		inlined := *input
+		inlined.Messages = messages
+		input = &inlined
 	}
 
-	// Extract config if provided
-	req := &ImageGenerationRequest{
-		Prompt:         prompt,
-		N:              1,
-		Size:           "1024x1024",
-		Quality:        "standard",
-		Style:          "vivid",
-		ResponseFormat: "url",
+	timeout, err := a.requestTimeout(input.Config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config for model '%s': %w", modelName, err)
+	}
+	ctx, err = contextWithConfigHeaders(ctx, input.Config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config for model '%s': %w", modelName, err)
 	}
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
 
-	// Apply config from input if available
-	if input.Config != nil {
-		if configMap, ok := input.Config.(map[string]interface{}); ok {
-			if n, ok := configMap["n"].(int); ok {
-				req.N = n
-			}
-			if size, ok := configMap["size"].(string); ok {
-				req.Size = size
-			}
-			if quality, ok := configMap["quality"].(string); ok {
-				req.Quality = quality
-			}
-			if style, ok := configMap["style"].(string); ok {
-				req.Style = style
-			}
-			if format, ok := configMap["response_format"].(string); ok {
-				req.ResponseFormat = format
+	input = a.clampMaxTokens(ctx, modelName, model.maxTokens, input)
+
+	// Models served through the Responses API, by default or for this request
+	api, err := a.requestAPI(modelName, model.api, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.checkInputTokens(input); err != nil {
+		return nil, err
+	}
+
+	// Wait for rate-limit capacity; the tokens taken are corrected with the actual usage
+	taken, err := a.limiter.wait(ctx, estimateRequestTokens(input))
+	if err != nil {
+		return nil, timeoutError(ctx, err)
+	}
+	var resp *ai.ModelResponse
+	defer func() {
+		if resp != nil {
+			a.limiter.reconcile(taken, resp.Usage)
+		}
+	}()
+
+	if api == APIResponses {
+		resp, err = a.generateResponse(ctx, modelName, input, cb)
+		if err != nil {
+			return nil, timeoutError(ctx, err)
+		}
+		a.dropRepairNulls(input, resp)
+		if err := a.validateToolArguments(input, resp); err != nil {
+			return nil, err
+		}
+		a.recordDataset(ctx, modelName, input, resp)
+		return resp, nil
+	}
+
+	// Models defined with Type "text" use the legacy Completions API
+	if api == apiCompletions {
+		resp, err = a.generateCompletion(ctx, modelName, input, cb)
+		if err != nil {
+			return nil, timeoutError(ctx, err)
+		}
+		a.recordDataset(ctx, modelName, input, resp)
+		return resp, nil
+	}
+
+	// Default: standard chat completion
+	// Build chat completion parameters
+	params, err := a.buildChatCompletionParams(input, modelName, cb != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle streaming vs non-streaming
+	if cb != nil {
+		resp, err = a.generateTextStream(ctx, params, input, cb)
+	} else {
+		resp, err = a.generateTextSync(ctx, params, input)
+	}
+	if err != nil {
+		return nil, timeoutError(ctx, err)
+	}
+	a.dropRepairNulls(input, resp)
+	if err := a.validateToolArguments(input, resp); err != nil {
+		return nil, err
+	}
+
+	a.recordDataset(ctx, modelName, input, resp)
+	return resp, nil
+}
+
+// clampMaxTokens returns the request with its maxOutputTokens lowered to limit, the model's
+// ModelDefinition.MaxTokens. Requests within the limit, or whose value is invalid and left for
+// extractConfigFromRequest to report, are returned as is. The config must be a map.
+func (a *AzureAIFoundry) clampMaxTokens(ctx context.Context, modelName string, limit int64, input *ai.ModelRequest) *ai.ModelRequest {
+	configMap, _ := input.Config.(map[string]interface{})
+	requested, ok := toInt64(configMap["maxOutputTokens"])
+	if limit <= 0 || !ok || requested <= limit {
+		return input
+	}
+
+	if a.Logger != nil {
+		a.Logger.LogAttrs(ctx, slog.LevelDebug, "azureaifoundry: clamping maxOutputTokens",
+			slog.String("model", modelName),
+			slog.Int64("requested", requested),
+			slog.Int64("maxTokens", limit),
+		)
+	}
+	config := maps.Clone(configMap)
+	config["maxOutputTokens"] = limit
+	clamped := *input
+	clamped.Config = config
+	return &clamped
+}
+
+// requestAPI returns the API surface for a request: the "api" config key overrides the model
+// default, which is chat completions when unset
+func (a *AzureAIFoundry) requestAPI(modelName, defaultAPI string, input *ai.ModelRequest) (string, error) {
+	if configMap, ok := requestConfig(input.Config); ok {
+		if raw, present := configMap["api"]; present {
+			switch api, _ := raw.(string); api {
+			case APIChat, APIResponses:
+				return api, nil
+			default:
+				return "", fmt.Errorf("invalid config for model '%s': api must be %q or %q, got %v", modelName, APIChat, APIResponses, raw)
 			}
 		}
 	}
+	return cmp.Or(defaultAPI, APIChat), nil
+}
 
+// generateImages handles image generation through Genkit's Generate interface
+func (a *AzureAIFoundry) generateImages(ctx context.Context, modelName string, input *ai.ModelRequest) (*ai.ModelResponse, error) {
 	// Generate images
-	resp, err := a.generateImagesInternal(ctx, modelName, req)
+	resp, err := a.generateImagesInternal(ctx, modelName, imageRequestFromInput(input))
 	if err != nil {
 		return nil, err
 	}
@@ -491,8 +1190,95 @@ func (a *AzureAIFoundry) generateImages(ctx context.Context, modelName string, i
 	}, nil
 }
 
-// generateSpeech handles text-to-speech through Genkit's Generate interface
-func (a *AzureAIFoundry) generateSpeech(ctx context.Context, modelName string, input *ai.ModelRequest) (*ai.ModelResponse, error) {
+// generateImageMedia handles image generation for models defined with DefineImageModel,
+// returning images as media parts and content-filter rejections as a blocked response
+func (a *AzureAIFoundry) generateImageMedia(ctx context.Context, modelName string, input *ai.ModelRequest) (*ai.ModelResponse, error) {
+	resp, err := a.generateImagesInternal(ctx, modelName, imageRequestFromInput(input))
+	if err != nil {
+		var apiErr *openai.Error
+		if errors.As(err, &apiErr) && (apiErr.Code == "content_policy_violation" || apiErr.Code == "content_filter") {
+			return &ai.ModelResponse{
+				Message: &ai.Message{
+					Role:    ai.RoleModel,
+					Content: []*ai.Part{},
+				},
+				FinishReason:  ai.FinishReasonBlocked,
+				FinishMessage: apiErr.Message,
+			}, nil
+		}
+		return nil, err
+	}
+
+	var content []*ai.Part
+	for _, img := range resp.Images {
+		if img.URL != "" {
+			content = append(content, ai.NewMediaPart("image/png", img.URL))
+		} else if img.B64JSON != "" {
+			content = append(content, ai.NewMediaPart("image/png", "data:image/png;base64,"+img.B64JSON))
+		}
+	}
+
+	return &ai.ModelResponse{
+		Message: &ai.Message{
+			Role:    ai.RoleModel,
+			Content: content,
+		},
+		FinishReason: ai.FinishReasonStop,
+	}, nil
+}
+
+// imageRequestFromInput builds an image generation request from a Genkit request
+func imageRequestFromInput(input *ai.ModelRequest) *ImageGenerationRequest {
+	// Extract prompt from messages
+	var prompt string
+	for _, msg := range input.Messages {
+		for _, part := range msg.Content {
+			if part.IsText() {
+				prompt += part.Text
+			}
+		}
+	}
+
+	// Extract config if provided
+	req := &ImageGenerationRequest{
+		Prompt:         prompt,
+		N:              1,
+		Size:           "1024x1024",
+		Quality:        "standard",
+		Style:          "vivid",
+		ResponseFormat: "url",
+	}
+
+	// Apply config from input if available
+	if input.Config != nil {
+		if configMap, ok := requestConfig(input.Config); ok {
+			if n, ok := toInt64(configMap["n"]); ok {
+				req.N = int(n)
+			}
+			if size, ok := configMap["size"].(string); ok {
+				req.Size = size
+			}
+			if quality, ok := configMap["quality"].(string); ok {
+				req.Quality = quality
+			}
+			if style, ok := configMap["style"].(string); ok {
+				req.Style = style
+			}
+			if format, ok := configMap["response_format"].(string); ok {
+				req.ResponseFormat = format
+			}
+			if user, ok := configMap["user"].(string); ok {
+				req.User = user
+			}
+		}
+	}
+
+	return req
+}
+
+// speechRequestFromInput builds a text-to-speech request from a Genkit request: the text of all
+// messages, with the "voice", "response_format" and "speed" config keys (alloy, mp3, 1.0 by default)
+func speechRequestFromInput(input *ai.ModelRequest) *TTSRequest {
 	// Extract text from messages
 	var text string
 	for _, msg := range input.Messages {
@@ -503,7 +1289,6 @@ func (a *AzureAIFoundry) generateSpeech(ctx context.Context, modelName string, i
 		}
 	}
 
-	// Extract config if provided
 	req := &TTSRequest{
 		Input:          text,
 		Voice:          "alloy",
@@ -512,22 +1297,25 @@ func (a *AzureAIFoundry) generateSpeech(ctx context.Context, modelName string, i
 	}
 
 	// Apply config from input if available
-	if input.Config != nil {
-		if configMap, ok := input.Config.(map[string]interface{}); ok {
-			if voice, ok := configMap["voice"].(string); ok {
-				req.Voice = voice
-			}
-			if format, ok := configMap["response_format"].(string); ok {
-				req.ResponseFormat = format
-			}
-			if speed, ok := configMap["speed"].(float64); ok {
-				req.Speed = speed
-			}
+	if configMap, ok := requestConfig(input.Config); ok {
+		if voice, ok := configMap["voice"].(string); ok {
+			req.Voice = voice
+		}
+		if format, ok := configMap["response_format"].(string); ok {
+			req.ResponseFormat = format
+		}
+		if speed, ok := toFloat64(configMap["speed"]); ok {
+			req.Speed = speed
 		}
 	}
 
+	return req
+}
+
+// generateSpeech handles text-to-speech through Genkit's Generate interface
+func (a *AzureAIFoundry) generateSpeech(ctx context.Context, modelName string, input *ai.ModelRequest) (*ai.ModelResponse, error) {
 	// Generate speech
-	resp, err := a.generateSpeechInternal(ctx, modelName, req)
+	resp, err := a.generateSpeechInternal(ctx, modelName, speechRequestFromInput(input))
 	if err != nil {
 		return nil, err
 	}
@@ -545,10 +1333,10 @@ func (a *AzureAIFoundry) generateSpeech(ctx context.Context, modelName string, i
 }
 
 // transcribeAudioFromRequest handles speech-to-text through Genkit's Generate interface
-func (a *AzureAIFoundry) transcribeAudioFromRequest(ctx context.Context, modelName string, input *ai.ModelRequest) (*ai.ModelResponse, error) {
+func (a *AzureAIFoundry) transcribeAudioFromRequest(ctx context.Context, modelName string, input *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
 	// Extract audio from media parts
 	var audioData []byte
-	var filename string
+	var contentType string
 
 	for _, msg := range input.Messages {
 		for _, part := range msg.Content {
@@ -565,14 +1353,9 @@ func (a *AzureAIFoundry) transcribeAudioFromRequest(ctx context.Context, modelNa
 					}
 
 					// Extract format from media type
-					if strings.Contains(mediaText, "audio/mp3") || strings.Contains(mediaText, "audio/mpeg") {
-						filename = "audio.mp3"
-					} else if strings.Contains(mediaText, "audio/wav") {
-						filename = "audio.wav"
-					} else if strings.Contains(mediaText, "audio/opus") {
-						filename = "audio.opus"
-					} else {
-						filename = "audio.mp3" // default
+					contentType = part.ContentType
+					if contentType == "" && strings.HasPrefix(mediaText, "data:") {
+						contentType, _, _ = strings.Cut(strings.TrimPrefix(mediaText, "data:"), ";")
 					}
 				}
 			}
@@ -586,13 +1369,13 @@ func (a *AzureAIFoundry) transcribeAudioFromRequest(ctx context.Context, modelNa
 	// Extract config if provided
 	req := &STTRequest{
 		Audio:          audioData,
-		Filename:       filename,
+		ContentType:    contentType,
 		ResponseFormat: "json",
 	}
 
 	// Apply config from input if available
 	if input.Config != nil {
-		if configMap, ok := input.Config.(map[string]interface{}); ok {
+		if configMap, ok := requestConfig(input.Config); ok {
 			if lang, ok := configMap["language"].(string); ok {
 				req.Language = lang
 			}
@@ -602,27 +1385,48 @@ func (a *AzureAIFoundry) transcribeAudioFromRequest(ctx context.Context, modelNa
 			if format, ok := configMap["response_format"].(string); ok {
 				req.ResponseFormat = format
 			}
-			if temp, ok := configMap["temperature"].(float64); ok {
+			if temp, ok := toFloat64(configMap["temperature"]); ok {
 				req.Temperature = temp
 			}
 		}
 	}
 
-	// Transcribe audio
-	resp, err := a.transcribeAudioInternal(ctx, modelName, req)
+	// Transcribe audio, streaming partial text when the model supports it
+	var resp *STTResponse
+	var err error
+	if cb != nil && supportsTranscriptionStreaming(modelName) {
+		resp, err = a.TranscribeStream(ctx, modelName, req, func(ctx context.Context, delta string) error {
+			return cb(ctx, &ai.ModelResponseChunk{
+				Content: []*ai.Part{ai.NewTextPart(delta)},
+			})
+		})
+	} else {
+		resp, err = a.transcribeAudioInternal(ctx, modelName, req)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	message := &ai.Message{
+		Role:    ai.RoleModel,
+		Content: []*ai.Part{ai.NewTextPart(resp.Text)},
+	}
+	if len(resp.Segments) > 0 {
+		message.Metadata = map[string]any{"segments": resp.Segments}
+	}
+
 	return &ai.ModelResponse{
-		Message: &ai.Message{
-			Role:    ai.RoleModel,
-			Content: []*ai.Part{ai.NewTextPart(resp.Text)},
-		},
+		Message:      message,
 		FinishReason: ai.FinishReasonStop,
 	}, nil
 }
 
+// supportsTranscriptionStreaming reports whether a transcription model can stream partial
+// results. whisper-1 only returns the final transcript.
+func supportsTranscriptionStreaming(modelName string) bool {
+	return strings.Contains(strings.ToLower(modelName), "transcribe")
+}
+
 // hasMultimodalContent checks if a message contains multimodal content (text + images)
 func (a *AzureAIFoundry) hasMultimodalContent(msg *ai.Message) bool {
 	hasText := false
@@ -641,21 +1445,87 @@ func (a *AzureAIFoundry) hasMultimodalContent(msg *ai.Message) bool {
 	return hasMedia || (hasText && len(msg.Content) > 1)
 }
 
-// convertMessagesToOpenAI converts Genkit messages to OpenAI message format
-func (a *AzureAIFoundry) convertMessagesToOpenAI(messages []*ai.Message) []openai.ChatCompletionMessageParamUnion {
+// isImagePart reports whether a media part holds an image. Parts without a content type
+// are treated as images unless their data URI says otherwise.
+func isImagePart(part *ai.Part) bool {
+	if part.ContentType != "" {
+		return strings.HasPrefix(part.ContentType, "image/")
+	}
+	if strings.HasPrefix(part.Text, "data:") {
+		return strings.HasPrefix(part.Text, "data:image/")
+	}
+	return true
+}
+
+// imageURLFromPart returns a URL Azure accepts for an image part: remote URLs and data URIs
+// are passed through, raw base64 payloads are wrapped in a data URI using the part's content type
+func imageURLFromPart(part *ai.Part) string {
+	url := part.Text
+	if isRemoteURL(url) || strings.HasPrefix(url, "data:") {
+		return url
+	}
+
+	contentType := part.ContentType
+	if contentType == "" {
+		contentType = "image/png"
+	}
+	return "data:" + contentType + ";base64," + url
+}
+
+// toolCallID returns the ID pairing a tool call with its response. The model-issued ID is
+// carried in the Genkit Ref field; the tool name is only a fallback for requests without one.
+func toolCallID(ref, name string) string {
+	if ref != "" {
+		return ref
+	}
+	return fmt.Sprintf("call_%s", name)
+}
+
+// convertMessagesToOpenAI converts Genkit messages to OpenAI message format:
+//
+//   - system: one system message with the concatenated text parts. With developerRole set it
+//     is sent as a developer message, which reasoning models expect in place of the system role
+//   - user: a plain string when the message is text only, otherwise an array of text,
+//     image_url and input_audio content parts in the original order
+//   - model: one assistant message with its text and one function tool call per tool request
+//     part (ID from toolCallID, arguments JSON-encoded). The schema keeps content and tool
+//     calls apart, so text parts become a plain string when there is one and an array of text
+//     parts otherwise, both in their original order, and tool calls keep theirs. Splitting the
+//     message instead would separate tool calls from the tool messages that must follow them.
+//   - tool: one tool message per tool response part, linked to its call by toolCallID
+//
+// The "assistant", "developer" and "function" roles are treated as model, system and tool.
+// Any other role is an ErrUnsupportedRole error. Messages without content are dropped, as are
+// tool requests and responses that cannot be JSON-encoded. Audio parts that are not inline
+// wav or mp3 data are an error.
+func (a *AzureAIFoundry) convertMessagesToOpenAI(messages []*ai.Message, developerRole bool) ([]openai.ChatCompletionMessageParamUnion, error) {
 	var openAIMessages []openai.ChatCompletionMessageParamUnion
 
-	for _, msg := range messages {
+	for i, msg := range messages {
 		if len(msg.Content) == 0 {
 			continue // Skip messages with no content
 		}
+		role, err := messageRole(msg.Role)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
 
-		switch msg.Role {
+		switch role {
 		case ai.RoleSystem:
+			if developerRole {
+				openAIMessages = append(openAIMessages, openai.ChatCompletionMessageParamUnion{
+					OfDeveloper: &openai.ChatCompletionDeveloperMessageParam{
+						Content: openai.ChatCompletionDeveloperMessageParamContentUnion{
+							OfString: openai.String(a.messageText(msg)),
+						},
+					},
+				})
+				continue
+			}
 			openAIMessages = append(openAIMessages, openai.ChatCompletionMessageParamUnion{
 				OfSystem: &openai.ChatCompletionSystemMessageParam{
 					Content: openai.ChatCompletionSystemMessageParamContentUnion{
-						OfString: openai.String(msg.Content[0].Text),
+						OfString: openai.String(a.messageText(msg)),
 					},
 				},
 			})
@@ -669,16 +1539,28 @@ func (a *AzureAIFoundry) convertMessagesToOpenAI(messages []*ai.Message) []opena
 					if part.IsText() {
 						contentParts = append(contentParts, openai.ChatCompletionContentPartUnionParam{
 							OfText: &openai.ChatCompletionContentPartTextParam{
-								Text: part.Text,
+								Text: a.prepareText(part.Text),
+							},
+						})
+					} else if part.IsMedia() && isAudioPart(part) {
+						data, format, err := inputAudioFromPart(part)
+						if err != nil {
+							return nil, err
+						}
+						contentParts = append(contentParts, openai.ChatCompletionContentPartUnionParam{
+							OfInputAudio: &openai.ChatCompletionContentPartInputAudioParam{
+								InputAudio: openai.ChatCompletionContentPartInputAudioInputAudioParam{
+									Data:   data,
+									Format: format,
+								},
 							},
 						})
-					} else if part.IsMedia() {
-						// Handle image/media content
-						// Media parts store the URL in the Text field
+					} else if part.IsMedia() && isImagePart(part) {
+						// Media parts store the URL (remote or data URI) in the Text field
 						contentParts = append(contentParts, openai.ChatCompletionContentPartUnionParam{
 							OfImageURL: &openai.ChatCompletionContentPartImageParam{
 								ImageURL: openai.ChatCompletionContentPartImageImageURLParam{
-									URL: part.Text,
+									URL: imageURLFromPart(part),
 								},
 							},
 						})
@@ -697,19 +1579,19 @@ func (a *AzureAIFoundry) convertMessagesToOpenAI(messages []*ai.Message) []opena
 				openAIMessages = append(openAIMessages, openai.ChatCompletionMessageParamUnion{
 					OfUser: &openai.ChatCompletionUserMessageParam{
 						Content: openai.ChatCompletionUserMessageParamContentUnion{
-							OfString: openai.String(msg.Content[0].Text),
+							OfString: openai.String(a.messageText(msg)),
 						},
 					},
 				})
 			}
 		case ai.RoleModel:
-			// Extract all content parts and tool requests
-			var textContent string
+			// Extract text parts and tool requests, each in their original order
+			var texts []string
 			var toolCalls []openai.ChatCompletionMessageToolCallUnionParam
 
 			for _, part := range msg.Content {
 				if part.IsText() {
-					textContent += part.Text
+					texts = append(texts, a.prepareText(part.Text))
 				} else if part.IsToolRequest() {
 					toolReq := part.ToolRequest
 					// Marshal the input to JSON string
@@ -719,7 +1601,7 @@ func (a *AzureAIFoundry) convertMessagesToOpenAI(messages []*ai.Message) []opena
 					}
 					toolCalls = append(toolCalls, openai.ChatCompletionMessageToolCallUnionParam{
 						OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
-							ID:   fmt.Sprintf("call_%s", toolReq.Name),
+							ID:   toolCallID(toolReq.Ref, toolReq.Name),
 							Type: "function",
 							Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
 								Name:      toolReq.Name,
@@ -730,10 +1612,17 @@ func (a *AzureAIFoundry) convertMessagesToOpenAI(messages []*ai.Message) []opena
 				}
 			}
 
-			assistantMsg := &openai.ChatCompletionAssistantMessageParam{
-				Content: openai.ChatCompletionAssistantMessageParamContentUnion{
-					OfString: openai.String(textContent),
-				},
+			assistantMsg := &openai.ChatCompletionAssistantMessageParam{}
+			if len(texts) > 1 {
+				// Keep separate text parts (e.g. before and after a tool call) apart
+				for _, text := range texts {
+					assistantMsg.Content.OfArrayOfContentParts = append(assistantMsg.Content.OfArrayOfContentParts,
+						openai.ChatCompletionAssistantMessageParamContentArrayOfContentPartUnion{
+							OfText: &openai.ChatCompletionContentPartTextParam{Text: text},
+						})
+				}
+			} else {
+				assistantMsg.Content.OfString = openai.String(strings.Join(texts, ""))
 			}
 
 			if len(toolCalls) > 0 {
@@ -748,17 +1637,16 @@ func (a *AzureAIFoundry) convertMessagesToOpenAI(messages []*ai.Message) []opena
 			for _, part := range msg.Content {
 				if part.IsToolResponse() {
 					toolResp := part.ToolResponse
-					// Marshal the output to JSON string for content
-					outputJSON, err := json.Marshal(toolResp.Output)
+					output, err := toolOutputText(toolResp.Output)
 					if err != nil {
 						continue
 					}
 					openAIMessages = append(openAIMessages, openai.ChatCompletionMessageParamUnion{
 						OfTool: &openai.ChatCompletionToolMessageParam{
 							Content: openai.ChatCompletionToolMessageParamContentUnion{
-								OfString: openai.String(string(outputJSON)),
+								OfString: openai.String(output),
 							},
-							ToolCallID: fmt.Sprintf("call_%s", toolResp.Name),
+							ToolCallID: toolCallID(toolResp.Ref, toolResp.Name),
 						},
 					})
 				}
@@ -766,88 +1654,658 @@ func (a *AzureAIFoundry) convertMessagesToOpenAI(messages []*ai.Message) []opena
 		}
 	}
 
-	return openAIMessages
+	return openAIMessages, nil
+}
+
+// toolOutputText returns the content sent to the model for a tool result. String outputs
+// (plain text or pre-serialized JSON) are passed through verbatim; anything else is JSON-encoded.
+func toolOutputText(output any) (string, error) {
+	if text, ok := output.(string); ok {
+		return text, nil
+	}
+	outputJSON, err := json.Marshal(output)
+	if err != nil {
+		return "", err
+	}
+	return string(outputJSON), nil
+}
+
+// messageText concatenates all text parts of a message
+func (a *AzureAIFoundry) messageText(msg *ai.Message) string {
+	var sb strings.Builder
+	for _, part := range msg.Content {
+		if part.IsText() {
+			sb.WriteString(part.Text)
+		}
+	}
+	return a.prepareText(sb.String())
+}
+
+// prepareText applies optional text transformations before a text part is sent
+func (a *AzureAIFoundry) prepareText(text string) string {
+	if a.MinifyWhitespace {
+		return minifyWhitespace(text)
+	}
+	return text
+}
+
+// minifyWhitespace collapses runs of spaces and tabs, trims trailing spaces and
+// squeezes consecutive blank lines, leaving fenced code blocks (```) unchanged
+func minifyWhitespace(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+	blank := false
+
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			out = append(out, strings.TrimRight(line, " \t"))
+			blank = false
+			continue
+		}
+		if inFence {
+			out = append(out, line)
+			continue
+		}
+
+		collapsed := strings.Join(strings.Fields(line), " ")
+		if collapsed == "" {
+			if blank {
+				continue // Squeeze consecutive blank lines
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		out = append(out, collapsed)
+	}
+
+	return strings.Join(out, "\n")
 }
 
 // extractConfig extracts and validates configuration values from a ModelRequest
 type modelConfig struct {
-	maxTokens   *int64
-	temperature *float64
-	topP        *float64
-	toolChoice  string
+	maxTokens        *int64
+	temperature      *float64
+	topP             *float64
+	frequencyPenalty *float64
+	presencePenalty  *float64
+	stopSequences    []string
+	seed             *int64
+	reasoningEffort  string
+	toolChoice       string // "auto", "required", "none" or "function" (see toolFunction)
+	toolFunction     string // Name of the tool to force when toolChoice is "function"
+	parallelTools    *bool
+	n                *int64
+	logprobs         bool
+	topLogprobs      *int64
+	user             string
+	cacheKey         string // Prompt cache routing key
+	cacheRetention   string // Prompt cache retention: "in-memory" or "24h"
+	serviceTier      string // Processing tier: "auto", "default", "flex", "scale" or "priority"
+	store            *bool  // Keep the completion in Azure stored completions
+	metadata         map[string]string
+	logitBias        map[string]int64
+	audioVoice       string // Voice for spoken output; audio output is requested when set
+	audioFormat      string // Spoken output format (defaults to wav)
 }
 
 // extractConfigFromRequest safely extracts configuration values from request
-func (a *AzureAIFoundry) extractConfigFromRequest(input *ai.ModelRequest) *modelConfig {
-	config := &modelConfig{}
+func (a *AzureAIFoundry) extractConfigFromRequest(input *ai.ModelRequest) (*modelConfig, error) {
+	config := &modelConfig{user: a.User}
+
+	// A tool choice set on the request takes precedence over the "toolChoice" config key
+	switch input.ToolChoice {
+	case "":
+	case ai.ToolChoiceAuto, ai.ToolChoiceRequired, ai.ToolChoiceNone:
+		config.toolChoice = string(input.ToolChoice)
+	default:
+		return nil, fmt.Errorf(`tool choice must be "auto", "required" or "none", got %q`, input.ToolChoice)
+	}
 
 	if input.Config == nil {
-		return config
+		return config, nil
 	}
 
-	configMap, ok := input.Config.(map[string]interface{})
+	configMap, ok := requestConfig(input.Config)
 	if !ok {
-		return config
+		return config, nil
 	}
 
-	if maxTokens, ok := configMap["maxOutputTokens"].(int); ok {
-		val := int64(maxTokens)
-		config.maxTokens = &val
+	if raw, present := configMap["maxOutputTokens"]; present {
+		maxTokens, ok := toInt64(raw)
+		if !ok || maxTokens <= 0 {
+			return nil, fmt.Errorf("maxOutputTokens must be a positive integer, got %v", raw)
+		}
+		config.maxTokens = &maxTokens
 	}
-	if temp, ok := configMap["temperature"].(float64); ok {
+	if raw, present := configMap["temperature"]; present {
+		temp, ok := toFloat64(raw)
+		if !ok {
+			return nil, fmt.Errorf("temperature must be a number, got %T", raw)
+		}
+		if !(temp >= 0 && temp <= 2) {
+			return nil, fmt.Errorf("temperature must be between 0 and 2, got %v", temp)
+		}
 		config.temperature = &temp
 	}
-	if topP, ok := configMap["topP"].(float64); ok {
+	if raw, present := configMap["topP"]; present {
+		topP, ok := toFloat64(raw)
+		if !ok {
+			return nil, fmt.Errorf("topP must be a number, got %T", raw)
+		}
+		if !(topP >= 0 && topP <= 1) {
+			return nil, fmt.Errorf("topP must be between 0 and 1, got %v", topP)
+		}
 		config.topP = &topP
 	}
-	if toolChoice, ok := configMap["toolChoice"].(string); ok {
-		config.toolChoice = toolChoice
+	if raw, present := configMap["toolChoice"]; present && input.ToolChoice == "" {
+		if name, ok := toolChoiceFunctionName(raw); ok {
+			config.toolChoice = "function"
+			config.toolFunction = name
+		} else if toolChoice, ok := raw.(string); ok {
+			config.toolChoice = toolChoice
+		} else {
+			return nil, fmt.Errorf(`toolChoice must be "auto", "required", "none", "function:<name>" or {"type": "function", "function": {"name": ...}}, got %v`, raw)
+		}
 	}
-
-	return config
-}
-
-// buildChatCompletionParams builds OpenAI chat completion parameters from Genkit request
-func (a *AzureAIFoundry) buildChatCompletionParams(input *ai.ModelRequest, modelName string) openai.ChatCompletionNewParams {
-	messages := a.convertMessagesToOpenAI(input.Messages)
-
-	params := openai.ChatCompletionNewParams{
-		Model:    openai.ChatModel(modelName),
-		Messages: messages,
+	if raw, present := configMap["parallelToolCalls"]; present {
+		parallel, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("parallelToolCalls must be a boolean, got %v", raw)
+		}
+		config.parallelTools = &parallel
 	}
-
-	// Apply configuration if provided
-	config := a.extractConfigFromRequest(input)
-	if config.maxTokens != nil {
-		params.MaxTokens = openai.Int(*config.maxTokens)
+	if raw, present := configMap["user"]; present {
+		user, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("user must be a string, got %T", raw)
+		}
+		config.user = user
 	}
-	if config.temperature != nil {
-		params.Temperature = openai.Float(*config.temperature)
+	if raw, present := configMap["audio"]; present {
+		voice, format, err := toAudioOutput(raw)
+		if err != nil {
+			return nil, fmt.Errorf("audio: %w", err)
+		}
+		config.audioVoice = voice
+		config.audioFormat = format
 	}
-	if config.topP != nil {
-		params.TopP = openai.Float(*config.topP)
+	if raw, present := configMap["logitBias"]; present {
+		bias, err := toLogitBias(raw)
+		if err != nil {
+			return nil, fmt.Errorf("logitBias: %w", err)
+		}
+		config.logitBias = bias
 	}
-
-	// Handle tools
-	if len(input.Tools) > 0 {
-		var tools []openai.ChatCompletionToolUnionParam
-		for _, tool := range input.Tools {
-			// Convert Genkit tool definition to OpenAI function tool format
-			funcDef := openai.FunctionDefinitionParam{
-				Name: tool.Name,
-			}
-			if tool.Description != "" {
-				funcDef.Description = openai.String(tool.Description)
-			}
-			if tool.InputSchema != nil {
-				funcDef.Parameters = tool.InputSchema
-			}
-			tools = append(tools, openai.ChatCompletionFunctionTool(funcDef))
+	if raw, present := configMap["promptCacheKey"]; present {
+		key, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("promptCacheKey must be a string, got %T", raw)
 		}
-		params.Tools = tools
-
-		// Set tool choice if specified in config
-		switch config.toolChoice {
+		config.cacheKey = key
+	}
+	if raw, present := configMap["promptCacheRetention"]; present {
+		retention, _ := raw.(string)
+		switch retention {
+		case "in-memory", "24h":
+			config.cacheRetention = retention
+		default:
+			return nil, fmt.Errorf(`promptCacheRetention must be "in-memory" or "24h", got %v`, raw)
+		}
+	}
+	if raw, present := configMap["serviceTier"]; present {
+		tier, _ := raw.(string)
+		switch tier {
+		case "auto", "default", "flex", "scale", "priority":
+			config.serviceTier = tier
+		default:
+			return nil, fmt.Errorf(`serviceTier must be "auto", "default", "flex", "scale" or "priority", got %v`, raw)
+		}
+	}
+	if raw, present := configMap["store"]; present {
+		store, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("store must be a boolean, got %v", raw)
+		}
+		config.store = &store
+	}
+	if raw, present := configMap["metadata"]; present {
+		metadata, err := toMetadata(raw)
+		if err != nil {
+			return nil, fmt.Errorf("metadata: %w", err)
+		}
+		config.metadata = metadata
+	}
+
+	// Penalties must be within [-2.0, 2.0]
+	penalties := []struct {
+		key string
+		dst **float64
+	}{
+		{"frequencyPenalty", &config.frequencyPenalty},
+		{"presencePenalty", &config.presencePenalty},
+	}
+	for _, p := range penalties {
+		raw, present := configMap[p.key]
+		if !present {
+			continue
+		}
+		val, ok := toFloat64(raw)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a number, got %T", p.key, raw)
+		}
+		if val < -2.0 || val > 2.0 {
+			return nil, fmt.Errorf("%s must be between -2.0 and 2.0, got %v", p.key, val)
+		}
+		*p.dst = &val
+	}
+
+	if raw, present := configMap["stopSequences"]; present {
+		stop, err := toStringSlice(raw)
+		if err != nil {
+			return nil, fmt.Errorf("stopSequences: %w", err)
+		}
+		if len(stop) > maxStopSequences {
+			return nil, fmt.Errorf("stopSequences accepts at most %d entries, got %d", maxStopSequences, len(stop))
+		}
+		config.stopSequences = stop
+	}
+
+	if raw, present := configMap["reasoningEffort"]; present {
+		effort, _ := raw.(string)
+		switch effort {
+		case "minimal", "low", "medium", "high":
+			config.reasoningEffort = effort
+		default:
+			return nil, fmt.Errorf("reasoningEffort must be one of minimal, low, medium, high, got %v", raw)
+		}
+	}
+
+	if raw, present := configMap["seed"]; present {
+		seed, ok := toInt64(raw)
+		if !ok {
+			return nil, fmt.Errorf("seed must be an integer, got %v", raw)
+		}
+		config.seed = &seed
+	}
+
+	if raw, present := configMap["n"]; present {
+		n, ok := toInt64(raw)
+		if !ok || n < 1 {
+			return nil, fmt.Errorf("n must be a positive integer, got %v", raw)
+		}
+		config.n = &n
+	}
+
+	if raw, present := configMap["logprobs"]; present {
+		logprobs, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("logprobs must be a boolean, got %v", raw)
+		}
+		config.logprobs = logprobs
+	}
+	if raw, present := configMap["topLogprobs"]; present {
+		top, ok := toInt64(raw)
+		if !ok || top < 0 || top > maxTopLogprobs {
+			return nil, fmt.Errorf("topLogprobs must be an integer between 0 and %d, got %v", maxTopLogprobs, raw)
+		}
+		// top_logprobs requires logprobs
+		config.logprobs = true
+		config.topLogprobs = &top
+	}
+
+	return config, nil
+}
+
+// toAudioOutput reads the "audio" config value, {"voice": "alloy", "format": "mp3"}, that
+// requests spoken output. The format defaults to wav.
+func toAudioOutput(v interface{}) (string, string, error) {
+	var audio struct {
+		Voice  string `json:"voice"`
+		Format string `json:"format"`
+	}
+	if err := cloneJSON(v, &audio); err != nil {
+		return "", "", fmt.Errorf(`must be an object such as {"voice": "alloy", "format": "wav"}, got %T`, v)
+	}
+	if audio.Voice == "" {
+		return "", "", fmt.Errorf("voice is required")
+	}
+
+	switch audio.Format {
+	case "":
+		audio.Format = "wav"
+	case "wav", "mp3", "aac", "flac", "opus", "pcm16":
+	default:
+		return "", "", fmt.Errorf("format must be one of wav, mp3, aac, flac, opus, pcm16, got %q", audio.Format)
+	}
+	return audio.Voice, audio.Format, nil
+}
+
+// toLogitBias converts a logitBias config value, a map from token ID to bias in [-100, 100],
+// into the string-keyed form of the API. Integer-keyed maps (e.g. map[int]int) are accepted.
+func toLogitBias(v interface{}) (map[string]int64, error) {
+	var raw map[string]interface{}
+	if err := cloneJSON(v, &raw); err != nil || raw == nil {
+		return nil, fmt.Errorf("must be a map of token IDs to biases, got %T", v)
+	}
+
+	bias := make(map[string]int64, len(raw))
+	for token, value := range raw {
+		if id, err := strconv.ParseInt(token, 10, 64); err != nil || id < 0 {
+			return nil, fmt.Errorf("token ID must be a non-negative integer, got %q", token)
+		}
+		b, ok := toInt64(value)
+		if !ok || b < -100 || b > 100 {
+			return nil, fmt.Errorf("bias for token %s must be an integer between -100 and 100, got %v", token, value)
+		}
+		bias[token] = b
+	}
+	return bias, nil
+}
+
+// Limits Azure places on stored completion metadata
+const (
+	maxMetadataPairs       = 16
+	maxMetadataKeyLength   = 64
+	maxMetadataValueLength = 512
+)
+
+// toMetadata converts a metadata config value to string key/value pairs within Azure's limits
+func toMetadata(v interface{}) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := cloneJSON(v, &raw); err != nil || raw == nil {
+		return nil, fmt.Errorf("must be a map of strings to strings, got %T", v)
+	}
+	if len(raw) > maxMetadataPairs {
+		return nil, fmt.Errorf("at most %d pairs are allowed, got %d", maxMetadataPairs, len(raw))
+	}
+
+	metadata := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if len(key) > maxMetadataKeyLength {
+			return nil, fmt.Errorf("key %q is longer than %d characters", key, maxMetadataKeyLength)
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("value for %q must be a string, got %T", key, value)
+		}
+		if len(s) > maxMetadataValueLength {
+			return nil, fmt.Errorf("value for %q is longer than %d characters", key, maxMetadataValueLength)
+		}
+		metadata[key] = s
+	}
+	return metadata, nil
+}
+
+// toolChoiceFunctionName extracts the tool name from a toolChoice that forces a specific function,
+// given either as "function:<name>" or as {"type": "function", "function": {"name": "<name>"}}
+func toolChoiceFunctionName(v interface{}) (string, bool) {
+	switch choice := v.(type) {
+	case string:
+		name, found := strings.CutPrefix(choice, "function:")
+		return name, found && name != ""
+	case map[string]interface{}:
+		if choiceType, _ := choice["type"].(string); choiceType != "function" {
+			return "", false
+		}
+		function, _ := choice["function"].(map[string]interface{})
+		name, _ := function["name"].(string)
+		return name, name != ""
+	}
+	return "", false
+}
+
+// validateToolFunction checks that a forced tool choice names one of the request's tools
+func validateToolFunction(name string, tools []*ai.ToolDefinition) error {
+	for _, tool := range tools {
+		if tool.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("toolChoice names tool '%s', which is not among the request's tools", name)
+}
+
+// toInt64 converts an integral config value to int64.
+// JSON-decoded numbers arrive as float64 (or json.Number with UseNumber) and are accepted
+// when they have no fractional part.
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), n <= math.MaxInt64
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+		if f, err := n.Float64(); err == nil {
+			return toInt64(f)
+		}
+	case float32:
+		return toInt64(float64(n))
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt64 && n < math.MaxInt64 {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+// maxStopSequences is the maximum number of stop sequences accepted by Azure OpenAI
+const maxStopSequences = 4
+
+// toStringSlice converts a string or list config value to []string.
+// JSON-decoded lists arrive as []interface{} and are handled as well.
+func toStringSlice(v interface{}) ([]string, error) {
+	switch vals := v.(type) {
+	case string:
+		return []string{vals}, nil
+	case []string:
+		return vals, nil
+	case []interface{}:
+		out := make([]string, 0, len(vals))
+		for i, item := range vals {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("entry %d must be a string, got %T", i, item)
+			}
+			out = append(out, str)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("must be a string or list of strings, got %T", v)
+}
+
+// toFloat64 converts a numeric config value to float64
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	if i, ok := toInt64(v); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
+
+// buildChatCompletionParams builds OpenAI chat completion parameters from Genkit request
+func (a *AzureAIFoundry) buildChatCompletionParams(input *ai.ModelRequest, modelName string, streaming bool) (openai.ChatCompletionNewParams, error) {
+	messages, err := a.convertMessagesToOpenAI(mergeSystemMessages(input.Messages, a.MergeSystemMessages), isReasoningModel(modelName))
+	if err != nil {
+		return openai.ChatCompletionNewParams{}, fmt.Errorf("invalid messages for model '%s': %w", modelName, err)
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel(modelName),
+		Messages: messages,
+	}
+
+	// Ask for a final chunk carrying token usage when streaming
+	if streaming {
+		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: openai.Bool(true),
+		}
+	}
+
+	// Apply configuration if provided
+	config, err := a.extractConfigFromRequest(input)
+	if err != nil {
+		return params, fmt.Errorf("invalid config for model '%s': %w", modelName, err)
+	}
+	if isReasoningModel(modelName) {
+		// Reasoning models take max_completion_tokens and reject sampling parameters,
+		// penalties, logprobs and stop sequences
+		if config.maxTokens != nil {
+			params.MaxCompletionTokens = openai.Int(*config.maxTokens)
+		}
+		if config.reasoningEffort != "" {
+			params.ReasoningEffort = shared.ReasoningEffort(config.reasoningEffort)
+		}
+	} else {
+		if config.maxTokens != nil {
+			params.MaxTokens = openai.Int(*config.maxTokens)
+		}
+		if config.temperature != nil {
+			params.Temperature = openai.Float(*config.temperature)
+		}
+		if config.topP != nil {
+			params.TopP = openai.Float(*config.topP)
+		}
+		if len(config.logitBias) > 0 {
+			params.LogitBias = config.logitBias
+		}
+		if config.frequencyPenalty != nil {
+			params.FrequencyPenalty = openai.Float(*config.frequencyPenalty)
+		}
+		if config.presencePenalty != nil {
+			params.PresencePenalty = openai.Float(*config.presencePenalty)
+		}
+		if config.logprobs {
+			params.Logprobs = openai.Bool(true)
+			if config.topLogprobs != nil {
+				params.TopLogprobs = openai.Int(*config.topLogprobs)
+			}
+		}
+		switch len(config.stopSequences) {
+		case 0:
+		case 1:
+			params.Stop = openai.ChatCompletionNewParamsStopUnion{
+				OfString: openai.String(config.stopSequences[0]),
+			}
+		default:
+			params.Stop = openai.ChatCompletionNewParamsStopUnion{
+				OfStringArray: config.stopSequences,
+			}
+		}
+	}
+	if config.seed != nil {
+		params.Seed = openai.Int(*config.seed)
+	}
+	if config.n != nil {
+		params.N = openai.Int(*config.n)
+	}
+	if config.user != "" {
+		params.User = openai.String(config.user)
+	}
+	if supportsPromptCaching(modelName) {
+		if config.cacheKey != "" {
+			params.PromptCacheKey = openai.String(config.cacheKey)
+		}
+		if config.cacheRetention != "" {
+			params.PromptCacheRetention = openai.ChatCompletionNewParamsPromptCacheRetention(config.cacheRetention)
+		}
+	}
+	if config.serviceTier != "" {
+		params.ServiceTier = openai.ChatCompletionNewParamsServiceTier(config.serviceTier)
+	}
+	if config.store != nil {
+		params.Store = openai.Bool(*config.store)
+	}
+	if len(config.metadata) > 0 {
+		params.Metadata = config.metadata
+	}
+	if config.audioVoice != "" {
+		params.Modalities = []string{"text", "audio"}
+		params.Audio = openai.ChatCompletionAudioParam{
+			Voice:  openai.ChatCompletionAudioParamVoice(config.audioVoice),
+			Format: openai.ChatCompletionAudioParamFormat(config.audioFormat),
+		}
+	}
+
+	// Request JSON output when the caller asked for structured output
+	if input.Output != nil && input.Output.Format == ai.OutputFormatJSON {
+		if input.Output.Schema != nil {
+			params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+				OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+					JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+						Name:   "output",
+						Schema: input.Output.Schema,
+					},
+				},
+			}
+		} else {
+			params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+				OfJSONObject: &openai.ResponseFormatJSONObjectParam{},
+			}
+		}
+	}
+
+	// Handle tools
+	if len(input.Tools) > 0 {
+		var tools []openai.ChatCompletionToolUnionParam
+		for _, tool := range input.Tools {
+			// Convert Genkit tool definition to OpenAI function tool format
+			funcDef := openai.FunctionDefinitionParam{
+				Name: tool.Name,
+			}
+			if tool.Description != "" {
+				funcDef.Description = openai.String(tool.Description)
+			}
+			if tool.InputSchema != nil {
+				if a.AutoToolSchemaRepair {
+					schema, strict := repairToolSchema(tool.InputSchema)
+					funcDef.Parameters = schema
+					if strict {
+						funcDef.Strict = openai.Bool(true)
+					}
+				} else {
+					funcDef.Parameters = tool.InputSchema
+				}
+			}
+			tools = append(tools, openai.ChatCompletionFunctionTool(funcDef))
+		}
+		params.Tools = tools
+
+		// Leave parallel_tool_calls unset unless configured so Azure's default applies
+		if config.parallelTools != nil {
+			params.ParallelToolCalls = openai.Bool(*config.parallelTools)
+		}
+
+		// Set tool choice if specified in config
+		switch config.toolChoice {
 		case "auto":
 			params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{
 				OfAuto: openai.String(string(openai.ChatCompletionToolChoiceOptionAutoAuto)),
@@ -860,33 +2318,54 @@ func (a *AzureAIFoundry) buildChatCompletionParams(input *ai.ModelRequest, model
 			params.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{
 				OfAuto: openai.String(string(openai.ChatCompletionToolChoiceOptionAutoNone)),
 			}
+		case "function":
+			if err := validateToolFunction(config.toolFunction, input.Tools); err != nil {
+				return params, fmt.Errorf("invalid config for model '%s': %w", modelName, err)
+			}
+			params.ToolChoice = openai.ToolChoiceOptionFunctionToolChoice(openai.ChatCompletionNamedToolChoiceFunctionParam{
+				Name: config.toolFunction,
+			})
 		}
 	}
 
-	return params
+	return params, nil
 }
 
 // generateTextSync handles synchronous text generation
 func (a *AzureAIFoundry) generateTextSync(ctx context.Context, params openai.ChatCompletionNewParams, originalInput *ai.ModelRequest) (*ai.ModelResponse, error) {
-	resp, err := a.client.Chat.Completions.New(ctx, params)
+	var resp *openai.ChatCompletion
+	var httpResp *http.Response
+	err := a.withRetry(ctx, func() error {
+		var err error
+		resp, err = a.api.NewChatCompletion(ctx, params, option.WithResponseInto(&httpResp))
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("chat completion failed for model '%s': %w", params.Model, err)
+		return nil, fmt.Errorf("chat completion failed for model '%s': %w", params.Model, classifyError(err))
 	}
 
-	return a.convertResponse(resp, originalInput), nil
-}
-
-// toolCallAccumulator holds tool call information during streaming
-type toolCallAccumulator struct {
-	id        string
-	name      string
-	arguments strings.Builder
+	result, err := a.convertResponse(resp, originalInput)
+	if err != nil {
+		return nil, err
+	}
+	setServedBy(result, httpResp)
+	a.setRawResponse(result, resp)
+	return result, nil
 }
 
-// generateTextStream handles streaming text generation
+// generateTextStream handles streaming text generation.
+// With "n" > 1 every choice is accumulated separately; only the first is forwarded to the callback.
 func (a *AzureAIFoundry) generateTextStream(ctx context.Context, params openai.ChatCompletionNewParams, originalInput *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	// A stop from WithStreamStop cancels only this request, leaving ctx intact
+	reqCtx, cancelReq := context.WithCancel(ctx)
+	defer cancelReq()
+	if signal := streamStopSignal(ctx); signal != nil {
+		defer context.AfterFunc(signal, cancelReq)()
+	}
+
 	// Note: Stream parameter is automatically set by NewStreaming
-	stream := a.client.Chat.Completions.NewStreaming(ctx, params)
+	var httpResp *http.Response
+	stream := a.api.NewChatCompletionStreaming(reqCtx, params, option.WithResponseInto(&httpResp))
 	defer func() {
 		if err := stream.Close(); err != nil {
 			// Log stream close error but don't override the main error
@@ -894,95 +2373,196 @@ func (a *AzureAIFoundry) generateTextStream(ctx context.Context, params openai.C
 		}
 	}()
 
-	var fullText strings.Builder
-	toolCallsMap := make(map[int]*toolCallAccumulator)
+	acc := StreamAccumulator{
+		PartialToolCalls:     a.StreamToolCalls,
+		MaxToolArgumentBytes: a.MaxToolArgumentBytes,
+		AudioContentType:     audioContentType(originalInput),
+	}
 
 	for stream.Next() {
-		chunk := stream.Current()
-		if len(chunk.Choices) > 0 {
-			delta := chunk.Choices[0].Delta
-
-			// Handle content streaming
-			if delta.Content != "" {
-				fullText.WriteString(delta.Content)
-
-				if cb != nil {
-					chunkResponse := &ai.ModelResponseChunk{
-						Content: []*ai.Part{
-							ai.NewTextPart(delta.Content),
-						},
-					}
-					if err := cb(ctx, chunkResponse); err != nil {
-						return nil, fmt.Errorf("streaming callback error: %w", err)
-					}
-				}
+		// Stop consuming the stream as soon as the caller goes away; the deferred Close releases it
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if streamStopped(ctx) {
+			break
+		}
+
+		parts, err := acc.Add(stream.Current())
+		if err != nil {
+			return nil, err
+		}
+		if cb == nil {
+			continue
+		}
+		for _, part := range parts {
+			if streamStopped(ctx) {
+				break
+			}
+			if err := cb(ctx, &ai.ModelResponseChunk{Content: []*ai.Part{part}}); err != nil {
+				return nil, fmt.Errorf("streaming callback error: %w", err)
 			}
+		}
+	}
 
-			// Handle tool call deltas
-			for _, toolCallDelta := range delta.ToolCalls {
-				idx := int(toolCallDelta.Index)
+	stopped := streamStopped(ctx)
+	if err := stream.Err(); err != nil && !stopped {
+		return nil, fmt.Errorf("stream error: %w", classifyError(err))
+	}
 
-				if toolCallsMap[idx] == nil {
-					toolCallsMap[idx] = &toolCallAccumulator{
-						id: toolCallDelta.ID,
-					}
-				}
+	trim := func(text string) string { return a.trimResultText(text, originalInput) }
+	candidates, err := acc.candidates(trim, a.EmptyResponseIsError && !stopped, stopped)
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range candidates {
+		a.applyToolCallBudget(candidate.Message)
+	}
 
-				// Accumulate function name and arguments
-				if toolCallDelta.Function.Name != "" {
-					toolCallsMap[idx].name = toolCallDelta.Function.Name
-				}
-				if toolCallDelta.Function.Arguments != "" {
-					toolCallsMap[idx].arguments.WriteString(toolCallDelta.Function.Arguments)
-				}
-			}
+	// No choices at all: handled like a synchronous response without choices
+	if len(candidates) == 0 {
+		if a.EmptyResponseIsError && !stopped {
+			return nil, ErrEmptyResponse
+		}
+		candidates = append(candidates, &Candidate{
+			Message:      &ai.Message{Role: ai.RoleModel, Content: []*ai.Part{}},
+			FinishReason: ai.FinishReasonUnknown,
+		})
+	}
+	if stopped {
+		for _, candidate := range candidates {
+			candidate.FinishReason = ai.FinishReasonInterrupted
+			candidate.FinishMessage = "stopped"
 		}
 	}
 
-	if err := stream.Err(); err != nil {
-		return nil, fmt.Errorf("stream error: %w", err)
+	result := candidatesResponse(candidates, acc.Usage())
+	setServiceTier(result, acc.serviceTier)
+	setServedBy(result, httpResp)
+	return result, nil
+}
+
+// partialToolRequestPart creates a tool request part for a call whose arguments are still streaming
+func partialToolRequestPart(toolCall *toolCallAccumulator) *ai.Part {
+	part := ai.NewToolRequestPart(&ai.ToolRequest{
+		Name: toolCall.name,
+		Ref:  toolCall.id,
+	})
+	part.Metadata = map[string]any{
+		"partial":   true,
+		"arguments": toolCall.arguments.String(),
 	}
+	return part
+}
 
-	// Build final message content
-	var content []*ai.Part
-	if fullText.Len() > 0 {
-		content = append(content, ai.NewTextPart(fullText.String()))
+// reasoningDeltaFields are the non-standard delta fields used by Azure-hosted reasoning models
+var reasoningDeltaFields = []string{"reasoning_content", "reasoning"}
+
+// reasoningFromDelta extracts streamed reasoning text from a chunk delta, if present
+func reasoningFromDelta(delta openai.ChatCompletionChunkChoiceDelta) string {
+	for _, key := range reasoningDeltaFields {
+		field, ok := delta.JSON.ExtraFields[key]
+		if !ok || !field.Valid() {
+			continue
+		}
+		var text string
+		if err := json.Unmarshal([]byte(field.Raw()), &text); err == nil && text != "" {
+			return text
+		}
 	}
+	return ""
+}
 
-	// Add tool calls to content
-	toolParts, err := a.convertToolCallsToParts(toolCallsMap)
+// audioDelta is a streamed piece of spoken output
+type audioDelta struct {
+	ID         string `json:"id"`
+	Data       string `json:"data"`       // Base64 audio bytes
+	Transcript string `json:"transcript"` // Transcript text
+}
+
+// audioFromDelta extracts spoken output from a streaming delta. The SDK has no typed field for it.
+func audioFromDelta(delta openai.ChatCompletionChunkChoiceDelta) (audioDelta, bool) {
+	field, ok := delta.JSON.ExtraFields["audio"]
+	if !ok || !field.Valid() {
+		return audioDelta{}, false
+	}
+	var audio audioDelta
+	if err := json.Unmarshal([]byte(field.Raw()), &audio); err != nil {
+		return audioDelta{}, false
+	}
+	return audio, audio.Data != "" || audio.Transcript != "" || audio.ID != ""
+}
+
+// streamedAudio accumulates spoken output across streaming deltas. Each delta is base64-encoded
+// on its own, so the bytes are decoded and joined rather than concatenating the base64 text.
+type streamedAudio struct {
+	id         string
+	data       bytes.Buffer
+	transcript strings.Builder
+}
+
+// add appends a delta and returns its decoded bytes
+func (s *streamedAudio) add(delta audioDelta) ([]byte, error) {
+	if delta.ID != "" {
+		s.id = delta.ID
+	}
+	s.transcript.WriteString(delta.Transcript)
+	if delta.Data == "" {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(delta.Data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert tool calls: %w", err)
+		return nil, fmt.Errorf("failed to decode streamed audio: %w", err)
 	}
-	content = append(content, toolParts...)
+	s.data.Write(data)
+	return data, nil
+}
 
-	return &ai.ModelResponse{
-		Message: &ai.Message{
-			Role:    ai.RoleModel,
-			Content: content,
-		},
-		FinishReason: ai.FinishReasonStop,
-	}, nil
+// result returns the accumulated audio, if any was streamed
+func (s *streamedAudio) result() (openai.ChatCompletionAudio, bool) {
+	if s.data.Len() == 0 && s.transcript.Len() == 0 {
+		return openai.ChatCompletionAudio{}, false
+	}
+	return openai.ChatCompletionAudio{
+		ID:         s.id,
+		Data:       base64.StdEncoding.EncodeToString(s.data.Bytes()),
+		Transcript: s.transcript.String(),
+	}, true
+}
+
+// newReasoningPart creates a reasoning part tagged in its metadata so UIs can render it apart from the answer
+func newReasoningPart(text string) *ai.Part {
+	part := ai.NewReasoningPart(text, nil)
+	part.Metadata["reasoning"] = true
+	return part
 }
 
 // convertToolCallsToParts converts accumulated tool calls to AI parts
-func (a *AzureAIFoundry) convertToolCallsToParts(toolCallsMap map[int]*toolCallAccumulator) ([]*ai.Part, error) {
+func convertToolCallsToParts(toolCallsMap map[int]*toolCallAccumulator) ([]*ai.Part, error) {
 	var parts []*ai.Part
 
-	for _, toolCall := range toolCallsMap {
+	// Preserve the order in which the model emitted the tool calls
+	indices := make([]int, 0, len(toolCallsMap))
+	for idx := range toolCallsMap {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+
+	for _, idx := range indices {
+		toolCall := toolCallsMap[idx]
 		if toolCall.name == "" {
 			continue
 		}
 
-		var args map[string]interface{}
-		if toolCall.arguments.Len() > 0 {
-			if err := json.Unmarshal([]byte(toolCall.arguments.String()), &args); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal tool arguments for '%s': %w", toolCall.name, err)
-			}
+		args, err := parseToolArguments(toolCall.arguments.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tool arguments for '%s': %w", toolCall.name, err)
 		}
 
 		parts = append(parts, ai.NewToolRequestPart(&ai.ToolRequest{
 			Name:  toolCall.name,
+			Ref:   toolCall.id,
 			Input: args,
 		}))
 	}
@@ -990,23 +2570,126 @@ func (a *AzureAIFoundry) convertToolCallsToParts(toolCallsMap map[int]*toolCallA
 	return parts, nil
 }
 
+// parseToolArguments decodes a tool call's JSON arguments. Absent arguments ("" or null)
+// decode to an empty map so tool handlers always receive a non-nil input.
+func parseToolArguments(raw string) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return args, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &args); err != nil {
+		return nil, err
+	}
+	if args == nil {
+		return map[string]interface{}{}, nil
+	}
+	return args, nil
+}
+
 // convertResponse converts OpenAI response to Genkit format
-func (a *AzureAIFoundry) convertResponse(resp *openai.ChatCompletion, originalInput *ai.ModelRequest) *ai.ModelResponse {
+func (a *AzureAIFoundry) convertResponse(resp *openai.ChatCompletion, originalInput *ai.ModelRequest) (*ai.ModelResponse, error) {
 	if len(resp.Choices) == 0 {
+		if a.EmptyResponseIsError {
+			return nil, ErrEmptyResponse
+		}
 		return &ai.ModelResponse{
 			Message: &ai.Message{
 				Role:    ai.RoleModel,
 				Content: []*ai.Part{},
 			},
 			FinishReason: ai.FinishReasonUnknown,
+		}, nil
+	}
+
+	candidates := make([]*Candidate, 0, len(resp.Choices))
+	for _, choice := range resp.Choices {
+		candidate, err := a.convertChoice(choice, resp.SystemFingerprint, originalInput)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	result := candidatesResponse(candidates, convertUsage(resp.Usage))
+	setServiceTier(result, string(resp.ServiceTier))
+	return result, nil
+}
+
+// newRefusalPart creates a text part for a model refusal, tagged in its metadata
+func newRefusalPart(refusal string) *ai.Part {
+	part := ai.NewTextPart(refusal)
+	part.Metadata = map[string]any{"refusal": true}
+	return part
+}
+
+// refusalCandidate marks a candidate whose model refused as blocked, with the refusal text
+// in the finish message and the message metadata
+func refusalCandidate(candidate *Candidate, refusal string) *Candidate {
+	if refusal == "" {
+		return candidate
+	}
+	candidate.FinishReason = ai.FinishReasonBlocked
+	candidate.FinishMessage = refusal
+	if candidate.Message.Metadata == nil {
+		candidate.Message.Metadata = make(map[string]any)
+	}
+	candidate.Message.Metadata["refusal"] = refusal
+	return candidate
+}
+
+// newAudioPart creates a media part from an audio response, keeping its transcript in metadata
+func newAudioPart(audio openai.ChatCompletionAudio, input *ai.ModelRequest) *ai.Part {
+	return audioPart(audio, audioContentType(input))
+}
+
+// audioPart creates a media part with spoken output of the given MIME type
+func audioPart(audio openai.ChatCompletionAudio, contentType string) *ai.Part {
+	part := ai.NewMediaPart(contentType, "data:"+contentType+";base64,"+audio.Data)
+	part.Metadata = map[string]any{
+		"transcript": audio.Transcript,
+		"audioId":    audio.ID,
+	}
+	return part
+}
+
+// audioContentType returns the MIME type of audio output requested via the "audio" config key
+// ({"voice": "alloy", "format": "wav"}); wav when unspecified
+func audioContentType(input *ai.ModelRequest) string {
+	format := "wav"
+	if configMap, ok := requestConfig(input.Config); ok {
+		if audio, ok := configMap["audio"].(map[string]interface{}); ok {
+			if f, ok := audio["format"].(string); ok && f != "" {
+				format = f
+			}
 		}
 	}
+	switch format {
+	case "mp3":
+		return "audio/mpeg"
+	case "pcm16":
+		return "audio/pcm"
+	default:
+		return "audio/" + format
+	}
+}
 
-	choice := resp.Choices[0]
+// convertChoice converts a single completion choice to a candidate
+func (a *AzureAIFoundry) convertChoice(choice openai.ChatCompletionChoice, systemFingerprint string, originalInput *ai.ModelRequest) (*Candidate, error) {
 	var content []*ai.Part
 
-	if choice.Message.Content != "" {
-		content = append(content, ai.NewTextPart(choice.Message.Content))
+	if text := a.trimResultText(choice.Message.Content, originalInput); text != "" {
+		content = append(content, ai.NewTextPart(text))
+	}
+
+	// A refusal replaces the content; keep it instead of returning an empty message
+	if choice.Message.Refusal != "" {
+		content = append(content, newRefusalPart(choice.Message.Refusal))
+	}
+
+	// Spoken output from audio-capable models
+	if choice.Message.Audio.Data != "" {
+		content = append(content, newAudioPart(choice.Message.Audio, originalInput))
 	}
 
 	// Handle tool calls
@@ -1014,40 +2697,179 @@ func (a *AzureAIFoundry) convertResponse(resp *openai.ChatCompletion, originalIn
 		for _, toolCall := range choice.Message.ToolCalls {
 			// Handle function tool calls (most common case)
 			if functionToolCall := toolCall.AsFunction(); functionToolCall.ID != "" {
-				var args map[string]interface{}
-				if err := json.Unmarshal([]byte(functionToolCall.Function.Arguments), &args); err != nil {
+				args, err := parseToolArguments(functionToolCall.Function.Arguments)
+				if err != nil {
 					// If we can't parse arguments, skip this tool call
 					continue
 				}
 				content = append(content, ai.NewToolRequestPart(&ai.ToolRequest{
 					Name:  functionToolCall.Function.Name,
+					Ref:   functionToolCall.ID,
 					Input: args,
 				}))
 			}
 		}
 	}
 
-	finishReason := a.convertFinishReason(choice.FinishReason)
+	// Legacy function_call responses (older API versions) carry a single call outside of
+	// tool_calls; surface it as a tool request so callers see the same pending-tool signal
+	if choice.Message.FunctionCall.Name != "" && len(choice.Message.ToolCalls) == 0 {
+		if args, err := parseToolArguments(choice.Message.FunctionCall.Arguments); err == nil {
+			content = append(content, ai.NewToolRequestPart(&ai.ToolRequest{
+				Name:  choice.Message.FunctionCall.Name,
+				Input: args,
+			}))
+		}
+	}
+
+	if a.EmptyResponseIsError && len(content) == 0 {
+		return nil, emptyResponseError(choice.FinishReason)
+	}
+
+	message := &ai.Message{
+		Role:    ai.RoleModel,
+		Content: content,
+	}
+	a.applyToolCallBudget(message)
+	setSystemFingerprint(message, systemFingerprint)
+	setLogprobs(message, convertLogprobs(choice.Logprobs.Content))
+
+	return refusalCandidate(&Candidate{
+		Index:         int(choice.Index),
+		Message:       message,
+		FinishReason:  convertFinishReason(choice.FinishReason),
+		FinishMessage: finishMessage(choice.FinishReason),
+	}, choice.Message.Refusal), nil
+}
 
+// trimResultText removes trailing whitespace and stop-sequence artifacts when TrimResult is set
+func (a *AzureAIFoundry) trimResultText(text string, input *ai.ModelRequest) string {
+	if !a.TrimResult {
+		return text
+	}
+
+	var stopSequences []string
+	if config, err := a.extractConfigFromRequest(input); err == nil {
+		stopSequences = config.stopSequences
+	}
+
+	for {
+		trimmed := strings.TrimRightFunc(text, unicode.IsSpace)
+		for _, stop := range stopSequences {
+			if stop != "" && strings.HasSuffix(trimmed, stop) {
+				trimmed = strings.TrimSuffix(trimmed, stop)
+			}
+		}
+		if trimmed == text {
+			return text
+		}
+		text = trimmed
+	}
+}
+
+// finishMessage returns the raw finish reason when the model stopped to call tools, so both
+// tool_calls and legacy function_call responses are recognizable as tool-pending, and when
+// the content filter stopped it, to tell a filtered response apart from other blocks
+func finishMessage(reason string) string {
+	switch reason {
+	case "tool_calls", "function_call", "content_filter":
+		return reason
+	}
+	return ""
+}
+
+// emptyResponseError is the EmptyResponseIsError failure for a choice without output.
+// It also matches ErrContentFiltered when the content filter stopped the choice.
+func emptyResponseError(finishReason string) error {
+	if finishReason == "content_filter" {
+		return fmt.Errorf("%w: %w", ErrEmptyResponse, ErrContentFiltered)
+	}
+	return fmt.Errorf("%w (finish reason %q)", ErrEmptyResponse, finishReason)
+}
+
+// convertUsage converts OpenAI token usage to Genkit format. Cached input and reasoning
+// tokens map to CachedContentTokens and ThoughtsTokens; the remaining breakdowns go in Custom.
+func convertUsage(u openai.CompletionUsage) *ai.GenerationUsage {
 	usage := &ai.GenerationUsage{}
-	if resp.Usage.PromptTokens > 0 {
-		usage.InputTokens = int(resp.Usage.PromptTokens)
-		usage.OutputTokens = int(resp.Usage.CompletionTokens)
-		usage.TotalTokens = int(resp.Usage.TotalTokens)
+	if u.PromptTokens > 0 {
+		usage.InputTokens = int(u.PromptTokens)
+		usage.OutputTokens = int(u.CompletionTokens)
+		usage.TotalTokens = int(u.TotalTokens)
+		usage.CachedContentTokens = int(u.PromptTokensDetails.CachedTokens)
+		usage.ThoughtsTokens = int(u.CompletionTokensDetails.ReasoningTokens)
+
+		details := map[string]int64{
+			"inputAudioTokens":         u.PromptTokensDetails.AudioTokens,
+			"outputAudioTokens":        u.CompletionTokensDetails.AudioTokens,
+			"acceptedPredictionTokens": u.CompletionTokensDetails.AcceptedPredictionTokens,
+			"rejectedPredictionTokens": u.CompletionTokensDetails.RejectedPredictionTokens,
+		}
+		for key, count := range details {
+			if count > 0 {
+				if usage.Custom == nil {
+					usage.Custom = make(map[string]float64)
+				}
+				usage.Custom[key] = float64(count)
+			}
+		}
 	}
+	return usage
+}
 
-	return &ai.ModelResponse{
-		Message: &ai.Message{
-			Role:    ai.RoleModel,
-			Content: content,
-		},
-		FinishReason: finishReason,
-		Usage:        usage,
+// setServiceTier records the processing tier that served a response in every candidate message
+func setServiceTier(resp *ai.ModelResponse, tier string) {
+	if tier == "" {
+		return
+	}
+	for _, message := range responseMessages(resp) {
+		if message.Metadata == nil {
+			message.Metadata = make(map[string]any)
+		}
+		message.Metadata["serviceTier"] = tier
+	}
+}
+
+// setSystemFingerprint records the backend configuration fingerprint used with seed for reproducibility
+func setSystemFingerprint(message *ai.Message, fingerprint string) {
+	if fingerprint == "" {
+		return
+	}
+	if message.Metadata == nil {
+		message.Metadata = make(map[string]any)
+	}
+	message.Metadata["systemFingerprint"] = fingerprint
+}
+
+// applyToolCallBudget drops tool requests beyond ToolCallBudget and records how many were dropped
+func (a *AzureAIFoundry) applyToolCallBudget(message *ai.Message) {
+	if a.ToolCallBudget <= 0 {
+		return
+	}
+
+	kept := make([]*ai.Part, 0, len(message.Content))
+	toolCalls, dropped := 0, 0
+	for _, part := range message.Content {
+		if part.IsToolRequest() {
+			if toolCalls >= a.ToolCallBudget {
+				dropped++
+				continue
+			}
+			toolCalls++
+		}
+		kept = append(kept, part)
+	}
+
+	if dropped > 0 {
+		message.Content = kept
+		if message.Metadata == nil {
+			message.Metadata = make(map[string]any)
+		}
+		message.Metadata["toolCallsTruncated"] = dropped
 	}
 }
 
 // convertFinishReason converts OpenAI finish reason to Genkit format
-func (a *AzureAIFoundry) convertFinishReason(reason string) ai.FinishReason {
+func convertFinishReason(reason string) ai.FinishReason {
 	switch reason {
 	case "stop":
 		return ai.FinishReasonStop
@@ -1063,46 +2885,104 @@ func (a *AzureAIFoundry) convertFinishReason(reason string) ai.FinishReason {
 }
 
 // embed handles embedding generation using Azure OpenAI
-func (a *AzureAIFoundry) embed(ctx context.Context, modelName string, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
-	var embeddings []*ai.Embedding
-
-	// Process each document
-	for _, doc := range req.Input {
-		var inputText string
-		// Extract text from document parts
-		for _, part := range doc.Content {
-			if part.IsText() {
-				inputText += part.Text
-			}
+func (a *AzureAIFoundry) embed(ctx context.Context, modelName string, req *ai.EmbedRequest, opts *EmbedderOptions) (*ai.EmbedResponse, error) {
+	if err := a.checkReady(); err != nil {
+		return nil, err
+	}
+	dimensions, err := embedDimensionsFromOptions(modelName, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	if dimensions == 0 && opts != nil {
+		dimensions = defaultEmbedDimensions(modelName, opts.Dimensions)
+	}
+	user := a.User
+	if optionsMap, ok := req.Options.(map[string]interface{}); ok {
+		if u, ok := optionsMap["user"].(string); ok {
+			user = u
 		}
+	}
+	timeout, err := a.requestTimeout(req.Options)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = contextWithConfigHeaders(ctx, req.Options)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancelTimeout := withTimeout(ctx, timeout)
+	defer cancelTimeout()
 
+	// Extract text from each document
+	var inputs []string
+	for _, doc := range req.Input {
+		inputText := documentText(doc)
 		if inputText == "" {
 			continue // Skip empty documents
 		}
+		inputs = append(inputs, inputText)
+	}
 
-		// Call Azure OpenAI embeddings API
-		resp, err := a.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
-			Model: openai.EmbeddingModel(modelName),
-			Input: openai.EmbeddingNewParamsInputUnion{
-				OfString: openai.String(inputText),
-			},
-		})
-		if err != nil {
-			return nil, fmt.Errorf("embedding generation failed for model '%s': %w", modelName, err)
-		}
+	// Split inputs into batches
+	batchSize := a.EmbeddingBatchSize
+	if opts != nil && opts.BatchSize > 0 {
+		batchSize = opts.BatchSize
+	}
+	if batchSize <= 0 {
+		batchSize = defaultEmbeddingBatchSize
+	}
+	var batches [][]string
+	for start := 0; start < len(inputs); start += batchSize {
+		batches = append(batches, inputs[start:min(start+batchSize, len(inputs))])
+	}
 
-		// Extract embeddings from response
-		if len(resp.Data) > 0 {
-			// Convert []float64 to []float32
-			embedding := make([]float32, len(resp.Data[0].Embedding))
-			for i, val := range resp.Data[0].Embedding {
-				embedding[i] = float32(val)
-			}
+	// Run batches through a bounded worker pool, keeping results in batch order
+	workers := a.EmbeddingConcurrency
+	if opts != nil && opts.Concurrency > 0 {
+		workers = opts.Concurrency
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	workers = min(workers, len(batches))
+	base64Encoding := opts != nil && opts.Base64
+	var progress func(ctx context.Context, progress EmbedProgress)
+	if opts != nil {
+		progress = opts.Progress
+	}
 
-			embeddings = append(embeddings, &ai.Embedding{
-				Embedding: embedding,
+	results := make([][]*ai.Embedding, len(batches))
+	var progressMu sync.Mutex
+	var done, batchesDone int
+	started := time.Now()
+	err = runBatches(ctx, len(batches), workers, func(ctx context.Context, i int) error {
+		var err error
+		results[i], err = a.embedBatch(ctx, modelName, batches[i], dimensions, user, base64Encoding)
+		if err != nil {
+			return err
+		}
+		if progress != nil {
+			progressMu.Lock()
+			defer progressMu.Unlock()
+			done += len(batches[i])
+			batchesDone++
+			elapsed := time.Since(started)
+			progress(ctx, EmbedProgress{
+				Done:      done,
+				Total:     len(inputs),
+				Batches:   batchesDone,
+				Remaining: time.Duration(float64(elapsed) / float64(done) * float64(len(inputs)-done)),
 			})
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, timeoutError(ctx, err)
+	}
+
+	var embeddings []*ai.Embedding
+	for i := range batches {
+		embeddings = append(embeddings, results[i]...)
 	}
 
 	return &ai.EmbedResponse{
@@ -1110,6 +2990,205 @@ func (a *AzureAIFoundry) embed(ctx context.Context, modelName string, req *ai.Em
 	}, nil
 }
 
+// embedDimensionsFromOptions reads the optional "dimensions" embed option.
+// Only text-embedding-3 models accept a custom output dimension.
+func embedDimensionsFromOptions(modelName string, options any) (int64, error) {
+	optionsMap, ok := options.(map[string]interface{})
+	if !ok {
+		return 0, nil
+	}
+	raw, present := optionsMap["dimensions"]
+	if !present {
+		return 0, nil
+	}
+
+	dimensions, ok := toInt64(raw)
+	if !ok || dimensions <= 0 {
+		return 0, fmt.Errorf("dimensions must be a positive integer, got %v", raw)
+	}
+
+	limits, known := lookupEmbeddingDimensions(modelName)
+	switch {
+	case !known:
+		return 0, fmt.Errorf("model '%s' does not support the dimensions option (text-embedding-3 models only)", modelName)
+	case limits.fixed:
+		return 0, fmt.Errorf("model '%s' has a fixed dimension of %d and does not accept a dimensions override", modelName, limits.max)
+	case dimensions > limits.max:
+		return 0, fmt.Errorf("model '%s' supports at most %d dimensions, got %d", modelName, limits.max, dimensions)
+	}
+	return dimensions, nil
+}
+
+// embedderInfo builds the Genkit embedder metadata, checking a configured dimension against
+// the model's limits
+func embedderInfo(modelName string, opts *EmbedderOptions) (*ai.EmbedderOptions, error) {
+	info := &ai.EmbedderOptions{
+		Label:    provider + "-" + modelName,
+		Supports: &ai.EmbedderSupports{Input: []string{"text"}},
+	}
+	limits, known := lookupEmbeddingDimensions(modelName)
+	if known {
+		info.Dimensions = int(limits.max)
+	}
+	if opts == nil {
+		return info, nil
+	}
+	if opts.Label != "" {
+		info.Label = opts.Label
+	}
+	if opts.Supports != nil {
+		info.Supports = opts.Supports
+	}
+	switch {
+	case opts.Dimensions < 0:
+		return nil, fmt.Errorf("embedder '%s': Dimensions must be positive, got %d", modelName, opts.Dimensions)
+	case opts.Dimensions == 0:
+	case known && limits.fixed && int64(opts.Dimensions) != limits.max:
+		return nil, fmt.Errorf("embedder '%s': model has a fixed dimension of %d, got %d", modelName, limits.max, opts.Dimensions)
+	case known && int64(opts.Dimensions) > limits.max:
+		return nil, fmt.Errorf("embedder '%s': model supports at most %d dimensions, got %d", modelName, limits.max, opts.Dimensions)
+	default:
+		info.Dimensions = opts.Dimensions
+	}
+	return info, nil
+}
+
+// defaultEmbedDimensions returns the "dimensions" parameter implied by an embedder's
+// configured size, or 0 when the model's native size applies
+func defaultEmbedDimensions(modelName string, configured int) int64 {
+	if configured <= 0 {
+		return 0
+	}
+	limits, known := lookupEmbeddingDimensions(modelName)
+	if known && (limits.fixed || int64(configured) == limits.max) {
+		return 0
+	}
+	return int64(configured)
+}
+
+// embeddingDimensions describes the output size of an embedding model
+type embeddingDimensions struct {
+	prefix string // Normalized model-name prefix
+	max    int64  // Native (maximum) dimension
+	fixed  bool   // Whether the dimension cannot be reduced
+}
+
+// embeddingModelDimensions lists known embedding models and their dimension limits
+var embeddingModelDimensions = []embeddingDimensions{
+	{prefix: "text-embedding-3-large", max: 3072},
+	{prefix: "text-embedding-3-small", max: 1536},
+	{prefix: "text-embedding-ada-002", max: 1536, fixed: true},
+}
+
+// lookupEmbeddingDimensions returns the dimension limits for an embedding model, if known
+func lookupEmbeddingDimensions(modelName string) (embeddingDimensions, bool) {
+	name := normalizeModelName(modelName)
+	for _, dims := range embeddingModelDimensions {
+		if strings.HasPrefix(name, dims.prefix) {
+			return dims, true
+		}
+	}
+	return embeddingDimensions{}, false
+}
+
+// embedBatch embeds a batch of inputs with a single Azure OpenAI call
+func (a *AzureAIFoundry) embedBatch(ctx context.Context, modelName string, inputs []string, dimensions int64, user string, base64Encoding bool) ([]*ai.Embedding, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	params := openai.EmbeddingNewParams{
+		Model: openai.EmbeddingModel(modelName),
+		Input: openai.EmbeddingNewParamsInputUnion{
+			OfArrayOfStrings: inputs,
+		},
+	}
+	if dimensions > 0 {
+		params.Dimensions = openai.Int(dimensions)
+	}
+	if user != "" {
+		params.User = openai.String(user)
+	}
+	if base64Encoding {
+		params.EncodingFormat = openai.EmbeddingNewParamsEncodingFormatBase64
+	}
+
+	// Wait for rate-limit capacity; the tokens taken are corrected with the actual usage
+	var estimate int
+	for _, text := range inputs {
+		estimate += estimateTokens(text)
+	}
+	taken, err := a.limiter.wait(ctx, estimate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Call Azure OpenAI embeddings API
+	var resp *openai.CreateEmbeddingResponse
+	err = a.withRetry(ctx, func() error {
+		var err error
+		resp, err = a.api.NewEmbedding(ctx, params)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding generation failed for model '%s': %w", modelName, classifyError(err))
+	}
+	a.limiter.reconcile(taken, &ai.GenerationUsage{TotalTokens: int(resp.Usage.TotalTokens)})
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf("embedding generation for model '%s' returned %d embeddings for %d inputs", modelName, len(resp.Data), len(inputs))
+	}
+
+	// Results carry their input index; place them accordingly
+	embeddings := make([]*ai.Embedding, len(inputs))
+	for _, data := range resp.Data {
+		if data.Index < 0 || int(data.Index) >= len(inputs) {
+			return nil, fmt.Errorf("embedding generation for model '%s' returned out-of-range index %d", modelName, data.Index)
+		}
+
+		var embedding []float32
+		if base64Encoding {
+			// The SDK types the vector as []float64, so decode the raw base64 string
+			var encoded string
+			if err := json.Unmarshal([]byte(data.JSON.Embedding.Raw()), &encoded); err != nil {
+				return nil, fmt.Errorf("embedding generation for model '%s' returned a non-base64 embedding: %w", modelName, err)
+			}
+			embedding, err = decodeBase64Embedding(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("embedding generation for model '%s' returned an invalid base64 embedding: %w", modelName, err)
+			}
+		} else {
+			// Convert []float64 to []float32
+			embedding = make([]float32, len(data.Embedding))
+			for i, val := range data.Embedding {
+				embedding[i] = float32(val)
+			}
+		}
+		embeddings[data.Index] = &ai.Embedding{
+			Embedding: embedding,
+		}
+	}
+	a.setRawEmbeddingResponse(embeddings, resp)
+
+	return embeddings, nil
+}
+
+// decodeBase64Embedding decodes a base64 embedding: little-endian IEEE 754 float32 values
+func decodeBase64Embedding(encoded string) ([]float32, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("decoded length %d is not a multiple of 4", len(data))
+	}
+
+	embedding := make([]float32, len(data)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return embedding, nil
+}
+
 // DefineCommonModels is a helper to define commonly used Azure OpenAI models
 func DefineCommonModels(a *AzureAIFoundry, g *genkit.Genkit) map[string]ai.Model {
 	models := make(map[string]ai.Model)
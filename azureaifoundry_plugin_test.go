@@ -0,0 +1,856 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/openai/openai-go/v3"
+)
+
+func TestMinifyWhitespace(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "collapses spaces and tabs",
+			in:   "Summarize   this\t\ttext,  please.   ",
+			want: "Summarize this text, please.",
+		},
+		{
+			name: "squeezes blank lines",
+			in:   "first\n\n\n   \nsecond",
+			want: "first\n\nsecond",
+		},
+		{
+			name: "preserves fenced code blocks",
+			in:   "Fix   this:\n```go\nfunc  main() {\n\tif  x {\n\n\n\t}\n}\n```  \nThanks   a lot",
+			want: "Fix this:\n```go\nfunc  main() {\n\tif  x {\n\n\n\t}\n}\n```\nThanks a lot",
+		},
+		{
+			name: "unterminated fence keeps the rest verbatim",
+			in:   "a  b\n```\nkeep   this",
+			want: "a b\n```\nkeep   this",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := minifyWhitespace(tt.in); got != tt.want {
+				t.Errorf("minifyWhitespace() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinifyWhitespaceAppliedToMessages(t *testing.T) {
+	a := &AzureAIFoundry{MinifyWhitespace: true}
+	messages, err := a.convertMessagesToOpenAI([]*ai.Message{
+		ai.NewSystemTextMessage("Be   brief."),
+		ai.NewUserTextMessage("Hello,\t\tworld   "),
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := messages[0].OfSystem.Content.OfString.Value; got != "Be brief." {
+		t.Errorf("system content = %q", got)
+	}
+	if got := messages[1].OfUser.Content.OfString.Value; got != "Hello, world" {
+		t.Errorf("user content = %q", got)
+	}
+}
+
+// toolCallCompletion returns a chat completion calling the named tools, one call each
+func toolCallCompletion(names ...string) map[string]any {
+	toolCalls := make([]any, len(names))
+	for i, name := range names {
+		toolCalls[i] = map[string]any{
+			"id":       fmt.Sprintf("call_%d", i),
+			"type":     "function",
+			"function": map[string]any{"name": name, "arguments": fmt.Sprintf(`{"n":%d}`, i)},
+		}
+	}
+	return map[string]any{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion",
+		"created": 0,
+		"model":   "gpt-4o",
+		"choices": []any{map[string]any{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": nil, "tool_calls": toolCalls},
+			"finish_reason": "tool_calls",
+		}},
+	}
+}
+
+// toolCallStream returns the chunks of a streamed answer calling the named tools, with each
+// call's arguments split across two deltas
+func toolCallStream(names ...string) []map[string]any {
+	var chunks []map[string]any
+	for i, name := range names {
+		chunks = append(chunks,
+			streamChunk(map[string]any{"tool_calls": []any{map[string]any{
+				"index": i, "id": fmt.Sprintf("call_%d", i), "type": "function",
+				"function": map[string]any{"name": name, "arguments": `{"n":`},
+			}}}, ""),
+			streamChunk(map[string]any{"tool_calls": []any{map[string]any{
+				"index": i, "function": map[string]any{"arguments": fmt.Sprintf(`%d}`, i)},
+			}}}, ""),
+		)
+	}
+	return append(chunks, streamChunk(map[string]any{}, "tool_calls"))
+}
+
+func TestToolCallBudget(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e"}
+	for _, streaming := range []bool{false, true} {
+		t.Run(fmt.Sprintf("streaming=%v", streaming), func(t *testing.T) {
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if streaming {
+					writeStream(w, toolCallStream(names...)...)
+					return
+				}
+				writeJSON(w, http.StatusOK, toolCallCompletion(names...))
+			})
+			a := newTestPlugin(t, server, func(a *AzureAIFoundry) { a.ToolCallBudget = 2 })
+
+			var cb func(context.Context, *ai.ModelResponseChunk) error
+			if streaming {
+				cb = func(context.Context, *ai.ModelResponseChunk) error { return nil }
+			}
+			resp, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("go")},
+			}, cb)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			requests := resp.ToolRequests()
+			if len(requests) != 2 {
+				t.Fatalf("got %d tool requests, want the budget of 2", len(requests))
+			}
+			for i, req := range requests {
+				if req.Name != names[i] || req.Ref != fmt.Sprintf("call_%d", i) {
+					t.Errorf("tool request %d = %s/%s, want %s/call_%d", i, req.Name, req.Ref, names[i], i)
+				}
+				if input, _ := req.Input.(map[string]any); input["n"] != float64(i) {
+					t.Errorf("tool request %d input = %v", i, req.Input)
+				}
+			}
+			if got := resp.Message.Metadata["toolCallsTruncated"]; got != 3 {
+				t.Errorf("toolCallsTruncated = %v, want 3", got)
+			}
+		})
+	}
+}
+
+func TestEmptyResponseIsError(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		for _, enabled := range []bool{false, true} {
+			t.Run(fmt.Sprintf("streaming=%v/enabled=%v", streaming, enabled), func(t *testing.T) {
+				server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+					if streaming {
+						writeStream(w, textStream("")...)
+						return
+					}
+					writeChatCompletion(w, "")
+				})
+				a := newTestPlugin(t, server, func(a *AzureAIFoundry) { a.EmptyResponseIsError = enabled })
+
+				var cb func(context.Context, *ai.ModelResponseChunk) error
+				if streaming {
+					cb = func(context.Context, *ai.ModelResponseChunk) error { return nil }
+				}
+				resp, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+					Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+				}, cb)
+
+				if enabled {
+					if !errors.Is(err, ErrEmptyResponse) {
+						t.Errorf("error = %v, want ErrEmptyResponse", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("error = %v, want an empty response", err)
+				}
+				if resp.Text() != "" || resp.FinishReason != ai.FinishReasonStop {
+					t.Errorf("response = %q (%s), want empty with finish reason stop", resp.Text(), resp.FinishReason)
+				}
+			})
+		}
+	}
+}
+
+func TestLegacyFunctionCallIsToolPending(t *testing.T) {
+	tests := []struct {
+		name    string
+		message map[string]any
+		reason  string
+	}{
+		{
+			name: "tool_calls",
+			message: map[string]any{"role": "assistant", "content": nil, "tool_calls": []any{map[string]any{
+				"id":       "call_0",
+				"type":     "function",
+				"function": map[string]any{"name": "search", "arguments": `{"q":"go"}`},
+			}}},
+			reason: "tool_calls",
+		},
+		{
+			name: "legacy function_call",
+			message: map[string]any{"role": "assistant", "content": nil, "function_call": map[string]any{
+				"name": "search", "arguments": `{"q":"go"}`,
+			}},
+			reason: "function_call",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, map[string]any{
+					"id":      "chatcmpl-test",
+					"object":  "chat.completion",
+					"created": 0,
+					"model":   "gpt-4o",
+					"choices": []any{map[string]any{"index": 0, "message": tt.message, "finish_reason": tt.reason}},
+				})
+			})
+			a := newTestPlugin(t, server, nil)
+
+			resp, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("find go")},
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.FinishReason != ai.FinishReasonStop || resp.FinishMessage != tt.reason {
+				t.Errorf("finish = %s (%q), want stop (%q)", resp.FinishReason, resp.FinishMessage, tt.reason)
+			}
+			requests := resp.ToolRequests()
+			if len(requests) != 1 {
+				t.Fatalf("got %d tool requests, want 1", len(requests))
+			}
+			if requests[0].Name != "search" || requests[0].Input.(map[string]any)["q"] != "go" {
+				t.Errorf("tool request = %+v, want search(q=go)", requests[0])
+			}
+		})
+	}
+}
+
+func TestTrimResult(t *testing.T) {
+	const raw = "The answer is 42.  \n<END>\n "
+	for _, streaming := range []bool{false, true} {
+		for _, enabled := range []bool{false, true} {
+			t.Run(fmt.Sprintf("streaming=%v/enabled=%v", streaming, enabled), func(t *testing.T) {
+				server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+					if streaming {
+						writeStream(w, textStream("The answer is 42.  \n", "<END>", "\n ")...)
+						return
+					}
+					writeChatCompletion(w, raw)
+				})
+				a := newTestPlugin(t, server, func(a *AzureAIFoundry) { a.TrimResult = enabled })
+
+				var cb func(context.Context, *ai.ModelResponseChunk) error
+				if streaming {
+					cb = func(context.Context, *ai.ModelResponseChunk) error { return nil }
+				}
+				resp, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+					Messages: []*ai.Message{ai.NewUserTextMessage("what is the answer?")},
+					Config:   map[string]interface{}{"stopSequences": []string{"<END>"}},
+				}, cb)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want := raw
+				if enabled {
+					want = "The answer is 42."
+				}
+				if resp.Text() != want {
+					t.Errorf("text = %q, want %q", resp.Text(), want)
+				}
+			})
+		}
+	}
+}
+
+func TestReasoningModelParams(t *testing.T) {
+	config := map[string]interface{}{
+		"maxOutputTokens":  256,
+		"temperature":      0.2,
+		"frequencyPenalty": 0.5,
+		"presencePenalty":  0.5,
+		"logprobs":         true,
+		"stopSequences":    []string{"END"},
+		"reasoningEffort":  "low",
+	}
+	sampling := []string{"max_tokens", "temperature", "frequency_penalty", "presence_penalty", "logprobs", "stop"}
+
+	tests := []struct {
+		model     string
+		reasoning bool
+	}{
+		{model: "gpt-5", reasoning: true},
+		{model: "gpt-5-mini", reasoning: true},
+		{model: "o3-mini", reasoning: true},
+		{model: "gpt-5-chat", reasoning: false},
+		{model: "gpt-4o", reasoning: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			a := &AzureAIFoundry{}
+			params, err := a.buildChatCompletionParams(&ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+				Config:   config,
+			}, tt.model, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(data, &body); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, key := range sampling {
+				if _, sent := body[key]; sent == tt.reasoning {
+					t.Errorf("%s sent = %v, want %v", key, sent, !tt.reasoning)
+				}
+			}
+			for _, key := range []string{"max_completion_tokens", "reasoning_effort"} {
+				if _, sent := body[key]; sent != tt.reasoning {
+					t.Errorf("%s sent = %v, want %v", key, sent, tt.reasoning)
+				}
+			}
+		})
+	}
+}
+
+func TestModelFamilyCapabilities(t *testing.T) {
+	tests := []struct {
+		model     string
+		reasoning bool
+		vision    bool
+	}{
+		{model: "gpt-5", reasoning: true, vision: true},
+		{model: "gpt-5-chat-latest", reasoning: false, vision: true},
+		{model: "o1", reasoning: true, vision: true},
+		{model: "o3-mini", reasoning: true, vision: false},
+		{model: "o4-mini", reasoning: true, vision: false},
+		{model: "gpt-4o", reasoning: false, vision: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			family, ok := lookupModelFamily(tt.model)
+			if !ok {
+				t.Fatalf("no family for %q", tt.model)
+			}
+			if family.reasoning != tt.reasoning || family.vision != tt.vision {
+				t.Errorf("reasoning = %v, vision = %v; want %v, %v", family.reasoning, family.vision, tt.reasoning, tt.vision)
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleUsesEndpointVerbatim(t *testing.T) {
+	for _, apiKey := range []string{"", "local-key"} {
+		t.Run(fmt.Sprintf("apiKey=%q", apiKey), func(t *testing.T) {
+			// The SDK falls back to OPENAI_API_KEY; keep a developer's key out of the keyless case
+			t.Setenv("OPENAI_API_KEY", "")
+			_ = os.Unsetenv("OPENAI_API_KEY")
+
+			var requests []*http.Request
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				if r.URL.Path == "/v1/embeddings" {
+					writeEmbeddings(t, w, decodeRequest(t, r))
+					return
+				}
+				writeChatCompletion(w, "ok")
+			})
+			a := &AzureAIFoundry{Endpoint: server.URL + "/v1/", APIKey: apiKey, OpenAICompatible: true}
+			a.Init(context.Background())
+
+			if _, err := a.generateText(context.Background(), "llama3", modelSettings{}, &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+			}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := a.embed(context.Background(), "nomic-embed-text", &ai.EmbedRequest{Input: docs(1)}, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			wantAuth := ""
+			if apiKey != "" {
+				wantAuth = "Bearer " + apiKey
+			}
+			for i, want := range []string{"/v1/chat/completions", "/v1/embeddings"} {
+				r := requests[i]
+				if r.URL.Path != want {
+					t.Errorf("path = %q, want %q", r.URL.Path, want)
+				}
+				if r.URL.Query().Has("api-version") {
+					t.Errorf("%s: api-version query sent", want)
+				}
+				if r.Header.Get("Api-Key") != "" {
+					t.Errorf("%s: Azure api-key header sent", want)
+				}
+				if got := r.Header.Get("Authorization"); got != wantAuth {
+					t.Errorf("%s: Authorization = %q, want %q", want, got, wantAuth)
+				}
+			}
+		})
+	}
+}
+
+func TestAzureModeRejectsPlainHTTPEndpoint(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "must use https") {
+			t.Errorf("Init panic = %v, want the https requirement", r)
+		}
+	}()
+	a := &AzureAIFoundry{Endpoint: "http://localhost:8000/v1/", APIKey: "local-key"}
+	a.Init(context.Background())
+}
+
+func TestParseToolArguments(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{raw: "", want: map[string]interface{}{}},
+		{raw: "  ", want: map[string]interface{}{}},
+		{raw: "{}", want: map[string]interface{}{}},
+		{raw: "null", want: map[string]interface{}{}},
+		{raw: `{"q":"go","limit":3}`, want: map[string]interface{}{"q": "go", "limit": float64(3)}},
+		{raw: `{"q":`, wantErr: true},
+		{raw: `["go"]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.raw), func(t *testing.T) {
+			got, err := parseToolArguments(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseToolArguments(%q) = %v, want an error", tt.raw, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseToolArguments(%q) = %#v, want %#v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyToolArgumentsAreEmptyObjects(t *testing.T) {
+	for _, streaming := range []bool{false, true} {
+		for _, arguments := range []string{"", "{}", `{"q":`} {
+			malformed := arguments == `{"q":`
+			t.Run(fmt.Sprintf("streaming=%v/%q", streaming, arguments), func(t *testing.T) {
+				server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+					toolCall := map[string]any{
+						"index":    0,
+						"id":       "call_0",
+						"type":     "function",
+						"function": map[string]any{"name": "now", "arguments": arguments},
+					}
+					if streaming {
+						writeStream(w,
+							streamChunk(map[string]any{"role": "assistant", "tool_calls": []any{toolCall}}, ""),
+							streamChunk(map[string]any{}, "tool_calls"),
+						)
+						return
+					}
+					completion := toolCallCompletion("now")
+					completion["choices"].([]any)[0].(map[string]any)["message"].(map[string]any)["tool_calls"] = []any{toolCall}
+					writeJSON(w, http.StatusOK, completion)
+				})
+				a := newTestPlugin(t, server, nil)
+
+				var cb func(context.Context, *ai.ModelResponseChunk) error
+				if streaming {
+					cb = func(context.Context, *ai.ModelResponseChunk) error { return nil }
+				}
+				resp, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+					Messages: []*ai.Message{ai.NewUserTextMessage("what time is it?")},
+				}, cb)
+
+				switch {
+				case malformed && streaming:
+					if err == nil || !strings.Contains(err.Error(), "failed to unmarshal tool arguments for 'now'") {
+						t.Errorf("error = %v, want the malformed arguments reported", err)
+					}
+				case malformed:
+					// Synchronous responses drop a tool call whose arguments can't be parsed
+					if err != nil || len(resp.ToolRequests()) != 0 {
+						t.Errorf("got %v, %v; want the tool call dropped", resp, err)
+					}
+				default:
+					if err != nil {
+						t.Fatal(err)
+					}
+					requests := resp.ToolRequests()
+					if len(requests) != 1 {
+						t.Fatalf("got %d tool requests, want 1", len(requests))
+					}
+					if input, ok := requests[0].Input.(map[string]interface{}); !ok || input == nil || len(input) != 0 {
+						t.Errorf("input = %#v, want an empty non-nil map", requests[0].Input)
+					}
+				}
+			})
+		}
+	}
+}
+
+// assistantMessage builds the expected assistant message with the given text and tool calls
+func assistantMessage(text string, toolCalls ...openai.ChatCompletionMessageToolCallUnionParam) openai.ChatCompletionMessageParamUnion {
+	msg := &openai.ChatCompletionAssistantMessageParam{ToolCalls: toolCalls}
+	msg.Content.OfString = openai.String(text)
+	return openai.ChatCompletionMessageParamUnion{OfAssistant: msg}
+}
+
+// functionCall builds the expected function tool call with the given ID, name and JSON arguments
+func functionCall(id, name, arguments string) openai.ChatCompletionMessageToolCallUnionParam {
+	return openai.ChatCompletionMessageToolCallUnionParam{
+		OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+			ID:       id,
+			Type:     "function",
+			Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{Name: name, Arguments: arguments},
+		},
+	}
+}
+
+func TestConvertMessagesToOpenAI(t *testing.T) {
+	tests := []struct {
+		name          string
+		messages      []*ai.Message
+		developerRole bool
+		want          []openai.ChatCompletionMessageParamUnion
+		wantErr       error
+	}{
+		{
+			name:     "system text parts are concatenated",
+			messages: []*ai.Message{ai.NewSystemMessage(ai.NewTextPart("You are terse. "), ai.NewTextPart("Answer in English."))},
+			want:     []openai.ChatCompletionMessageParamUnion{openai.SystemMessage("You are terse. Answer in English.")},
+		},
+		{
+			name:          "system becomes developer for reasoning models",
+			messages:      []*ai.Message{ai.NewSystemTextMessage("You are terse.")},
+			developerRole: true,
+			want:          []openai.ChatCompletionMessageParamUnion{openai.DeveloperMessage("You are terse.")},
+		},
+		{
+			name:     "text-only user message is a plain string",
+			messages: []*ai.Message{ai.NewUserTextMessage("Hello")},
+			want:     []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hello")},
+		},
+		{
+			name: "multi-part user message keeps part order",
+			messages: []*ai.Message{ai.NewUserMessage(
+				ai.NewTextPart("Compare"),
+				ai.NewMediaPart("image/jpeg", "https://example.com/a.jpg"),
+				ai.NewMediaPart("image/jpeg", "/9j/4AAQ"),
+				ai.NewMediaPart("audio/wav", "data:audio/wav;base64,UklGRg=="),
+				ai.NewTextPart("and describe the sound."),
+			)},
+			want: []openai.ChatCompletionMessageParamUnion{openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
+				openai.TextContentPart("Compare"),
+				openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: "https://example.com/a.jpg"}),
+				openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{URL: "data:image/jpeg;base64,/9j/4AAQ"}),
+				openai.InputAudioContentPart(openai.ChatCompletionContentPartInputAudioInputAudioParam{Data: "UklGRg==", Format: "wav"}),
+				openai.TextContentPart("and describe the sound."),
+			})},
+		},
+		{
+			name:     "text-only model message",
+			messages: []*ai.Message{ai.NewModelTextMessage("Hi!")},
+			want:     []openai.ChatCompletionMessageParamUnion{assistantMessage("Hi!")},
+		},
+		{
+			name: "tool calls keep their IDs, falling back to the tool name",
+			messages: []*ai.Message{ai.NewModelMessage(
+				ai.NewTextPart("Checking."),
+				ai.NewToolRequestPart(&ai.ToolRequest{Name: "weather", Ref: "call_abc", Input: map[string]any{"city": "Madrid"}}),
+				ai.NewToolRequestPart(&ai.ToolRequest{Name: "time", Input: map[string]any{}}),
+			)},
+			want: []openai.ChatCompletionMessageParamUnion{assistantMessage("Checking.",
+				functionCall("call_abc", "weather", `{"city":"Madrid"}`),
+				functionCall("call_time", "time", `{}`),
+			)},
+		},
+		{
+			name: "several model text parts stay apart",
+			messages: []*ai.Message{ai.NewModelMessage(
+				ai.NewTextPart("Before."),
+				ai.NewToolRequestPart(&ai.ToolRequest{Name: "search", Ref: "call_1", Input: map[string]any{"q": "go"}}),
+				ai.NewTextPart("After."),
+			)},
+			want: []openai.ChatCompletionMessageParamUnion{{OfAssistant: &openai.ChatCompletionAssistantMessageParam{
+				Content: openai.ChatCompletionAssistantMessageParamContentUnion{
+					OfArrayOfContentParts: []openai.ChatCompletionAssistantMessageParamContentArrayOfContentPartUnion{
+						{OfText: &openai.ChatCompletionContentPartTextParam{Text: "Before."}},
+						{OfText: &openai.ChatCompletionContentPartTextParam{Text: "After."}},
+					},
+				},
+				ToolCalls: []openai.ChatCompletionMessageToolCallUnionParam{functionCall("call_1", "search", `{"q":"go"}`)},
+			}}},
+		},
+		{
+			name: "one tool message per tool response",
+			messages: []*ai.Message{ai.NewMessage(ai.RoleTool, nil,
+				ai.NewToolResponsePart(&ai.ToolResponse{Name: "weather", Ref: "call_abc", Output: map[string]any{"temp": 21}}),
+				ai.NewToolResponsePart(&ai.ToolResponse{Name: "time", Output: "12:00"}),
+			)},
+			want: []openai.ChatCompletionMessageParamUnion{
+				openai.ToolMessage(`{"temp":21}`, "call_abc"),
+				openai.ToolMessage("12:00", "call_time"),
+			},
+		},
+		{
+			name: "role aliases",
+			messages: []*ai.Message{
+				ai.NewMessage("developer", nil, ai.NewTextPart("Be brief.")),
+				ai.NewMessage("assistant", nil, ai.NewTextPart("Sure.")),
+				ai.NewMessage("function", nil, ai.NewToolResponsePart(&ai.ToolResponse{Name: "now", Ref: "call_9", Output: "noon"})),
+			},
+			want: []openai.ChatCompletionMessageParamUnion{
+				openai.SystemMessage("Be brief."),
+				assistantMessage("Sure."),
+				openai.ToolMessage("noon", "call_9"),
+			},
+		},
+		{
+			name: "full tool round trip, skipping empty messages",
+			messages: []*ai.Message{
+				ai.NewSystemTextMessage("Use tools."),
+				ai.NewUserTextMessage("Weather in Madrid?"),
+				{Role: ai.RoleModel},
+				ai.NewModelMessage(ai.NewToolRequestPart(&ai.ToolRequest{Name: "weather", Ref: "call_1", Input: map[string]any{"city": "Madrid"}})),
+				ai.NewMessage(ai.RoleTool, nil, ai.NewToolResponsePart(&ai.ToolResponse{Name: "weather", Ref: "call_1", Output: "sunny"})),
+				ai.NewModelTextMessage("It is sunny."),
+			},
+			want: []openai.ChatCompletionMessageParamUnion{
+				openai.SystemMessage("Use tools."),
+				openai.UserMessage("Weather in Madrid?"),
+				assistantMessage("", functionCall("call_1", "weather", `{"city":"Madrid"}`)),
+				openai.ToolMessage("sunny", "call_1"),
+				assistantMessage("It is sunny."),
+			},
+		},
+		{
+			name:     "unknown role",
+			messages: []*ai.Message{ai.NewMessage("critic", nil, ai.NewTextPart("Hmm."))},
+			wantErr:  ErrUnsupportedRole,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &AzureAIFoundry{}
+			got, err := a.convertMessagesToOpenAI(tt.messages, tt.developerRole)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				gotJSON, _ := json.MarshalIndent(got, "", "  ")
+				wantJSON, _ := json.MarshalIndent(tt.want, "", "  ")
+				t.Errorf("convertMessagesToOpenAI() =\n%s\nwant\n%s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestModelTextAndToolCallOrderRoundTrips(t *testing.T) {
+	history := []*ai.Message{
+		ai.NewUserTextMessage("Weather and time in Madrid?"),
+		ai.NewModelMessage(
+			ai.NewTextPart("Checking the weather."),
+			ai.NewToolRequestPart(&ai.ToolRequest{Name: "weather", Ref: "call_1", Input: map[string]any{"city": "Madrid"}}),
+			ai.NewTextPart("And the time."),
+			ai.NewToolRequestPart(&ai.ToolRequest{Name: "time", Ref: "call_2", Input: map[string]any{"city": "Madrid"}}),
+		),
+		ai.NewMessage(ai.RoleTool, nil,
+			ai.NewToolResponsePart(&ai.ToolResponse{Name: "weather", Ref: "call_1", Output: "sunny"}),
+			ai.NewToolResponsePart(&ai.ToolResponse{Name: "time", Ref: "call_2", Output: "12:00"}),
+		),
+	}
+
+	// Each API is summarized as the sequence of what it received, in order
+	tests := []struct {
+		api  string
+		want []string
+	}{
+		{
+			// Chat keeps content and tool calls in separate fields; each keeps its own order
+			api: APIChat,
+			want: []string{
+				"user",
+				"assistant text: Checking the weather.", "assistant text: And the time.",
+				"assistant tool call: call_1 weather", "assistant tool call: call_2 time",
+				"tool: call_1 sunny", "tool: call_2 12:00",
+			},
+		},
+		{
+			// Responses input items interleave exactly
+			api: APIResponses,
+			want: []string{
+				"user",
+				"assistant text: Checking the weather.", "function_call: call_1 weather",
+				"assistant text: And the time.", "function_call: call_2 time",
+				"function_call_output: call_1 sunny", "function_call_output: call_2 12:00",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.api, func(t *testing.T) {
+			var got []string
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				body := decodeRequest(t, r)
+				if tt.api == APIResponses {
+					for _, raw := range body["input"].([]any) {
+						item := raw.(map[string]any)
+						switch {
+						case item["type"] == "function_call":
+							got = append(got, fmt.Sprintf("function_call: %v %v", item["call_id"], item["name"]))
+						case item["type"] == "function_call_output":
+							got = append(got, fmt.Sprintf("function_call_output: %v %v", item["call_id"], item["output"]))
+						case item["role"] == "assistant":
+							got = append(got, fmt.Sprintf("assistant text: %v", item["content"]))
+						default:
+							got = append(got, fmt.Sprint(item["role"]))
+						}
+					}
+					writeJSON(w, http.StatusOK, responsesResponse(outputMessage("Sunny, noon.")))
+					return
+				}
+				for _, raw := range body["messages"].([]any) {
+					msg := raw.(map[string]any)
+					switch msg["role"] {
+					case "assistant":
+						for _, part := range msg["content"].([]any) {
+							got = append(got, fmt.Sprintf("assistant text: %v", part.(map[string]any)["text"]))
+						}
+						for _, raw := range msg["tool_calls"].([]any) {
+							call := raw.(map[string]any)
+							got = append(got, fmt.Sprintf("assistant tool call: %v %v", call["id"], call["function"].(map[string]any)["name"]))
+						}
+					case "tool":
+						got = append(got, fmt.Sprintf("tool: %v %v", msg["tool_call_id"], msg["content"]))
+					default:
+						got = append(got, fmt.Sprint(msg["role"]))
+					}
+				}
+				writeChatCompletion(w, "Sunny, noon.")
+			})
+			a := newTestPlugin(t, server, nil)
+
+			if _, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+				Messages: history,
+				Config:   map[string]interface{}{"api": tt.api},
+			}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWithToolsUsesRegisteredModel(t *testing.T) {
+	var temperatures []any
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body := decodeRequest(t, r)
+		temperatures = append(temperatures, body["temperature"])
+		if len(temperatures) == 1 {
+			writeJSON(w, http.StatusOK, toolCallCompletion("lookup"))
+			return
+		}
+		writeChatCompletion(w, "Done.")
+	})
+	g, a := newTestGenkit(t, server, nil)
+	a.DefineModel(g, ModelDefinition{Name: "gpt-4o", Type: "chat"}, nil,
+		WithName("precise"), WithDefaults(map[string]any{"temperature": 0.2}))
+
+	resp, err := a.GenerateWithTools(context.Background(), "precise", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Look it up.")},
+		Tools:    []*ai.ToolDefinition{{Name: "lookup", InputSchema: map[string]any{"type": "object"}}},
+	}, ToolLoopOptions{
+		Executor: func(context.Context, *ai.ToolRequest) (any, error) { return "found", nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "Done." {
+		t.Errorf("got %q, want %q", resp.Text(), "Done.")
+	}
+	// Both calls go to the alias' deployment with its defaults
+	if want := []any{0.2, 0.2}; !reflect.DeepEqual(temperatures, want) {
+		t.Errorf("temperatures sent = %v, want %v", temperatures, want)
+	}
+}
+
+func TestUninitializedPluginReturnsErrNotInitialized(t *testing.T) {
+	a := &AzureAIFoundry{Endpoint: "https://example.openai.azure.com/", APIKey: "test-key"}
+	ctx := context.Background()
+	input := &ai.ModelRequest{Messages: []*ai.Message{ai.NewUserTextMessage("hi")}}
+
+	_, err := a.generateText(ctx, "gpt-4o", modelSettings{}, input, nil)
+	if !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("generate error = %v, want ErrNotInitialized", err)
+	}
+	_, err = a.generateText(ctx, "gpt-4o", modelSettings{}, input, func(context.Context, *ai.ModelResponseChunk) error { return nil })
+	if !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("streaming generate error = %v, want ErrNotInitialized", err)
+	}
+	_, err = a.embed(ctx, "text-embedding-3-small", &ai.EmbedRequest{Input: docs(1)}, nil)
+	if !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("embed error = %v, want ErrNotInitialized", err)
+	}
+}
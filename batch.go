@@ -0,0 +1,197 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/openai/openai-go/v3"
+)
+
+// BatchOptions configures GenerateBatch
+type BatchOptions struct {
+	MaxConcurrency     int // Upper bound on in-flight requests (default 4)
+	MinConcurrency     int // Lower bound the controller backs off to on 429 (default 1)
+	MaxThrottleRetries int // How many times a throttled request is re-queued before failing (default 3)
+
+	// ThrottleBaseDelay is the wait before re-queuing a throttled request that got no Retry-After,
+	// doubled on each further throttle (default 500ms)
+	ThrottleBaseDelay time.Duration
+}
+
+// BatchResult holds the outcome of a single request in a batch
+type BatchResult struct {
+	Index    int               // Position of the request in the input slice
+	Response *ai.ModelResponse // Response, if the request succeeded
+	Err      error             // Error, if the request failed
+}
+
+// GenerateBatch runs several generate requests concurrently and returns their results in input order.
+// Concurrency adapts to Azure throttling (AIMD): it is halved whenever a request is rejected with
+// HTTP 429 and grows back additively as requests succeed. Throttled requests are re-queued after
+// the server's Retry-After, or an exponential backoff when it sends none.
+func GenerateBatch(ctx context.Context, g *genkit.Genkit, requests [][]ai.GenerateOption, opts *BatchOptions) []BatchResult {
+	return runBatch(ctx, len(requests), opts, func(ctx context.Context, i int) (*ai.ModelResponse, error) {
+		return genkit.Generate(ctx, g, requests[i]...)
+	})
+}
+
+// runBatch drives a batch of n calls through an adaptive concurrency controller
+func runBatch(ctx context.Context, n int, opts *BatchOptions, call func(context.Context, int) (*ai.ModelResponse, error)) []BatchResult {
+	maxConc, minConc, maxRetries := 4, 1, 3
+	baseDelay := defaultRetryBaseDelay
+	if opts != nil {
+		if opts.ThrottleBaseDelay > 0 {
+			baseDelay = opts.ThrottleBaseDelay
+		}
+		if opts.MaxConcurrency > 0 {
+			maxConc = opts.MaxConcurrency
+		}
+		if opts.MinConcurrency > 0 {
+			minConc = opts.MinConcurrency
+		}
+		if opts.MaxThrottleRetries > 0 {
+			maxRetries = opts.MaxThrottleRetries
+		}
+	}
+	if minConc > maxConc {
+		minConc = maxConc
+	}
+
+	results := make([]BatchResult, n)
+	ctrl := newAIMDController(minConc, maxConc)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		results[i].Index = i
+
+		if err := ctrl.acquire(ctx); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for attempt := 0; ; attempt++ {
+				resp, err := call(ctx, i)
+				throttled := isThrottled(err)
+				ctrl.release(throttled)
+
+				if !throttled || attempt >= maxRetries {
+					results[i].Response = resp
+					results[i].Err = err
+					return
+				}
+
+				// Back off as the server asks, then wait for a slot under the reduced limit
+				delay, ok := retryAfter(err)
+				if !ok {
+					delay = min(baseDelay<<attempt, maxRetryDelay)
+				}
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					results[i].Err = ctx.Err()
+					return
+				case <-timer.C:
+				}
+				if err := ctrl.acquire(ctx); err != nil {
+					results[i].Err = err
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// isThrottled reports whether an error is an Azure rate-limit rejection
+func isThrottled(err error) bool {
+	var apiErr *openai.Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests
+}
+
+// aimdController limits in-flight calls using additive-increase/multiplicative-decrease
+type aimdController struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    float64
+	min      float64
+	max      float64
+	inFlight int
+}
+
+// newAIMDController creates a controller starting at the maximum concurrency
+func newAIMDController(minConc, maxConc int) *aimdController {
+	c := &aimdController{
+		limit: float64(maxConc),
+		min:   float64(minConc),
+		max:   float64(maxConc),
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// acquire blocks until a slot is available under the current limit or ctx is done
+func (c *aimdController) acquire(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.cond.Broadcast()
+	})
+	defer stop()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.inFlight >= int(c.limit) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c.cond.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.inFlight++
+	return nil
+}
+
+// release frees a slot and adjusts the limit based on whether the call was throttled
+func (c *aimdController) release(throttled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight--
+	if throttled {
+		c.limit = max(c.min, c.limit/2)
+	} else {
+		c.limit = min(c.max, c.limit+1/c.limit)
+	}
+	c.cond.Broadcast()
+}
@@ -0,0 +1,182 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestGenerateBatchAdaptsToThrottling(t *testing.T) {
+	const (
+		requests = 24
+		capacity = 2 // Requests the stub serves at once before answering 429
+	)
+
+	var inFlight, throttled atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body := decodeRequest(t, r)
+		if inFlight.Add(1) > capacity {
+			inFlight.Add(-1)
+			throttled.Add(1)
+			writeAPIError(w, http.StatusTooManyRequests, "429", "rate limited")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+		inFlight.Add(-1)
+
+		// Echo the prompt so results can be matched to requests
+		messages := body["messages"].([]any)
+		writeChatCompletion(w, messages[0].(map[string]any)["content"].(string))
+	})
+	g, a := newTestGenkit(t, server, nil)
+	model := a.DefineModel(g, ModelDefinition{Name: "gpt-4o", Type: "chat"}, nil)
+
+	batch := make([][]ai.GenerateOption, requests)
+	for i := range batch {
+		batch[i] = []ai.GenerateOption{ai.WithModel(model), ai.WithPrompt(fmt.Sprintf("request %d", i))}
+	}
+	results := GenerateBatch(context.Background(), g, batch, &BatchOptions{
+		MaxConcurrency:     8,
+		MaxThrottleRetries: requests,
+		ThrottleBaseDelay:  5 * time.Millisecond,
+	})
+
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("results[%d].Index = %d", i, result.Index)
+		}
+		if result.Err != nil {
+			t.Errorf("request %d failed: %v", i, result.Err)
+			continue
+		}
+		if got, want := result.Response.Text(), fmt.Sprintf("request %d", i); got != want {
+			t.Errorf("request %d got %q, want %q", i, got, want)
+		}
+	}
+
+	// At a fixed concurrency of 8 most calls of every wave would be throttled, again and again.
+	// Backing off keeps rejections to the occasional probe above the stub's capacity.
+	if n := throttled.Load(); n == 0 || n >= requests {
+		t.Errorf("throttled %d times for %d requests, want some but fewer than one per request", n, requests)
+	}
+}
+
+func TestGenerateBatchBacksOffThrottledRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string          // Retry-After-Ms header of the 429s; empty for none
+		wantGaps   []time.Duration // Minimum wait before each retry
+	}{
+		{name: "retry-after", retryAfter: "60", wantGaps: []time.Duration{60 * time.Millisecond, 60 * time.Millisecond}},
+		{name: "exponential", wantGaps: []time.Duration{20 * time.Millisecond, 40 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []time.Time
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, time.Now())
+				if len(calls) <= len(tt.wantGaps) {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After-Ms", tt.retryAfter)
+					}
+					writeAPIError(w, http.StatusTooManyRequests, "429", "rate limited")
+					return
+				}
+				writeChatCompletion(w, "ok")
+			})
+			g, a := newTestGenkit(t, server, nil)
+			model := a.DefineModel(g, ModelDefinition{Name: "gpt-4o", Type: "chat"}, nil)
+
+			results := GenerateBatch(context.Background(), g, [][]ai.GenerateOption{
+				{ai.WithModel(model), ai.WithPrompt("hi")},
+			}, &BatchOptions{MaxConcurrency: 1, ThrottleBaseDelay: 20 * time.Millisecond})
+
+			if err := results[0].Err; err != nil {
+				t.Fatal(err)
+			}
+			if len(calls) != len(tt.wantGaps)+1 {
+				t.Fatalf("made %d calls, want %d", len(calls), len(tt.wantGaps)+1)
+			}
+			for i, want := range tt.wantGaps {
+				if gap := calls[i+1].Sub(calls[i]); gap < want {
+					t.Errorf("retry %d came %v after the 429, want at least %v", i+1, gap, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateBatchBackoffHonorsContext(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "20")
+		writeAPIError(w, http.StatusTooManyRequests, "429", "rate limited")
+	})
+	g, a := newTestGenkit(t, server, nil)
+	model := a.DefineModel(g, ModelDefinition{Name: "gpt-4o", Type: "chat"}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := GenerateBatch(ctx, g, [][]ai.GenerateOption{{ai.WithModel(model), ai.WithPrompt("hi")}}, nil)
+
+	if !errors.Is(results[0].Err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the context's", results[0].Err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("returned after %v, want soon after the context ended", elapsed)
+	}
+}
+
+func TestAIMDController(t *testing.T) {
+	c := newAIMDController(1, 8)
+
+	c.inFlight = 1
+	c.release(true)
+	if c.limit != 4 {
+		t.Errorf("limit after throttle = %v, want 4", c.limit)
+	}
+	for range 3 {
+		c.inFlight = 1
+		c.release(true)
+	}
+	if c.limit != 1 {
+		t.Errorf("limit after repeated throttles = %v, want the minimum 1", c.limit)
+	}
+
+	c.inFlight = 1
+	c.release(false)
+	if c.limit != 2 {
+		t.Errorf("limit after success = %v, want 2", c.limit)
+	}
+
+	for range 100 {
+		c.inFlight = 1
+		c.release(false)
+	}
+	if c.limit != 8 {
+		t.Errorf("limit after many successes = %v, want the maximum 8", c.limit)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"github.com/firebase/genkit/go/ai"
+)
+
+// Candidate is one completion of a chat response. Requests with the "n" config key
+// greater than 1 return several candidates; see Candidates.
+type Candidate struct {
+	Index         int             // Choice index assigned by Azure
+	Message       *ai.Message     // Generated message
+	FinishReason  ai.FinishReason // Why this choice stopped
+	FinishMessage string          // Raw finish reason when the choice stopped to call tools
+}
+
+// candidatesResponse builds a ModelResponse from the first candidate and attaches the
+// full list to Custom when there is more than one
+func candidatesResponse(candidates []*Candidate, usage *ai.GenerationUsage) *ai.ModelResponse {
+	first := candidates[0]
+	resp := &ai.ModelResponse{
+		Message:       first.Message,
+		FinishReason:  first.FinishReason,
+		FinishMessage: first.FinishMessage,
+		Usage:         usage,
+	}
+	if len(candidates) > 1 {
+		resp.Custom = candidates
+	}
+	return resp
+}
+
+// responseMessages returns the message of every candidate of a response, starting with resp.Message
+func responseMessages(resp *ai.ModelResponse) []*ai.Message {
+	var messages []*ai.Message
+	if resp.Message != nil {
+		messages = append(messages, resp.Message)
+	}
+	for _, candidate := range Candidates(resp) {
+		if candidate.Message != nil && candidate.Message != resp.Message {
+			messages = append(messages, candidate.Message)
+		}
+	}
+	return messages
+}
+
+// Candidates returns every completion of a response generated with "n" > 1, in choice order.
+// The response's Message is the first candidate. Single-completion responses yield nil.
+func Candidates(resp *ai.ModelResponse) []*Candidate {
+	if resp == nil {
+		return nil
+	}
+	candidates, _ := resp.Custom.([]*Candidate)
+	return candidates
+}
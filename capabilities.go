@@ -0,0 +1,115 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// modelFamily describes the capabilities shared by a family of models
+type modelFamily struct {
+	prefix     string // Normalized model-name prefix identifying the family
+	tools      bool   // Supports tool (function) calling
+	systemRole bool   // Accepts system messages
+	reasoning  bool   // Reasoning model: uses max_completion_tokens and rejects sampling parameters
+	vision     bool   // Accepts image input
+	caching    bool   // Supports prompt caching of repeated prefixes
+}
+
+// modelFamilies is the capability table used by inferModelCapabilities.
+// More specific prefixes must come before the prefixes they extend.
+var modelFamilies = []modelFamily{
+	{prefix: "gpt-5-chat", tools: true, systemRole: true, vision: true, caching: true},
+	{prefix: "gpt-5", tools: true, systemRole: true, reasoning: true, vision: true, caching: true},
+	{prefix: "gpt-4.1", tools: true, systemRole: true, vision: true, caching: true},
+	{prefix: "gpt-4o", tools: true, systemRole: true, vision: true, caching: true},
+	{prefix: "gpt-4-turbo", tools: true, systemRole: true, vision: true},
+	{prefix: "gpt-4", tools: true, systemRole: true},
+	{prefix: "gpt-35-turbo", tools: true, systemRole: true},
+	{prefix: "gpt-3.5-turbo", tools: true, systemRole: true},
+	{prefix: "o1-mini", tools: false, systemRole: false, reasoning: true, caching: true},
+	{prefix: "o1-preview", tools: false, systemRole: false, reasoning: true, caching: true},
+	{prefix: "o1", tools: true, systemRole: true, reasoning: true, vision: true, caching: true},
+	{prefix: "o3", tools: true, systemRole: true, reasoning: true, caching: true},
+	{prefix: "o4", tools: true, systemRole: true, reasoning: true, caching: true},
+}
+
+// isReasoningModel reports whether a model belongs to a reasoning family
+func isReasoningModel(modelName string) bool {
+	family, ok := lookupModelFamily(modelName)
+	return ok && family.reasoning
+}
+
+// supportsPromptCaching reports whether a model accepts prompt caching parameters.
+// Unknown models are assumed to, so custom deployment names keep working.
+func supportsPromptCaching(modelName string) bool {
+	family, ok := lookupModelFamily(modelName)
+	return !ok || family.caching
+}
+
+// normalizeModelName lowercases a model name and strips surrounding whitespace
+func normalizeModelName(modelName string) string {
+	return strings.ToLower(strings.TrimSpace(modelName))
+}
+
+// fineTuneSeparators introduce the job or model ID that fine-tuned model names append to the base model
+var fineTuneSeparators = []string{".ft-", "-ft-", ":ft-", "_ft_"}
+
+// baseModelName returns the normalized name of the model a fine-tuned model was trained from:
+//
+//   - ft:gpt-4o-mini-2024-07-18:contoso::9abc -> gpt-4o-mini-2024-07-18 (OpenAI naming)
+//   - gpt-4o-mini-2024-07-18.ft-0e208cf3      -> gpt-4o-mini-2024-07-18 (Azure naming)
+//   - gpt-4o-mini-2024-07-18-ft-abc123        -> gpt-4o-mini-2024-07-18 (common deployment names)
+//   - ft-gpt-35-turbo-support                 -> gpt-35-turbo-support
+//
+// Other names are returned normalized but otherwise unchanged.
+func baseModelName(modelName string) string {
+	name := normalizeModelName(modelName)
+	if rest, ok := strings.CutPrefix(name, "ft:"); ok {
+		name, _, _ = strings.Cut(rest, ":")
+	}
+	name = strings.TrimPrefix(name, "ft-")
+	for _, sep := range fineTuneSeparators {
+		name, _, _ = strings.Cut(name, sep)
+	}
+	return strings.TrimSuffix(name, "-ft")
+}
+
+// lookupModelFamily returns the capability entry for a model name, if known. Fine-tuned
+// models resolve to the family of their base model.
+func lookupModelFamily(modelName string) (modelFamily, bool) {
+	name := baseModelName(modelName)
+	for _, family := range modelFamilies {
+		if strings.HasPrefix(name, family.prefix) {
+			return family, true
+		}
+	}
+	return modelFamily{}, false
+}
+
+// registeredCapabilities returns the ModelCapabilities entry for a deployment, if any
+func (a *AzureAIFoundry) registeredCapabilities(modelName string) (ai.ModelSupports, bool) {
+	name := normalizeModelName(modelName)
+	for registered, supports := range a.ModelCapabilities {
+		if normalizeModelName(registered) == name {
+			return supports, true
+		}
+	}
+	return ai.ModelSupports{}, false
+}
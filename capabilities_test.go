@@ -0,0 +1,98 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDefineModelDoesNotProbe(t *testing.T) {
+	var requests atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		writeAPIError(w, http.StatusNotFound, "DeploymentNotFound", "unexpected request")
+	})
+	g, a := newTestGenkit(t, server, nil)
+
+	// Capabilities are inferred from the name, so defining a deployment again costs nothing
+	a.DefineModel(g, ModelDefinition{Name: "gpt-4o", Type: "chat", SupportsMedia: true}, nil)
+	a.DefineModel(g, ModelDefinition{Name: "gpt-4o", Type: "chat", SupportsMedia: true}, nil, WithName("gpt-4o-again"))
+
+	if n := requests.Load(); n != 0 {
+		t.Errorf("defining models issued %d requests, want none", n)
+	}
+	for _, name := range []string{"gpt-4o", "gpt-4o-again"} {
+		if !IsDefinedModel(g, name) {
+			t.Errorf("model %q not defined", name)
+		}
+	}
+}
+
+func TestBaseModelName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"gpt-4o", "gpt-4o"},
+		{"  GPT-4o-Mini ", "gpt-4o-mini"},
+		{"ft:gpt-4o-mini-2024-07-18:contoso::9abc", "gpt-4o-mini-2024-07-18"},
+		{"ft:gpt-4o-2024-08-06:contoso:support:9abc", "gpt-4o-2024-08-06"},
+		{"gpt-4o-mini-2024-07-18.ft-0e208cf3", "gpt-4o-mini-2024-07-18"},
+		{"gpt-4o-mini-2024-07-18-ft-abc123", "gpt-4o-mini-2024-07-18"},
+		{"gpt-35-turbo-0125_ft_support", "gpt-35-turbo-0125"},
+		{"o4-mini-2025-04-16.ft-1234abcd", "o4-mini-2025-04-16"},
+		{"gpt-4.1-mini-ft", "gpt-4.1-mini"},
+		{"ft-gpt-35-turbo-support", "gpt-35-turbo-support"},
+		{"my-deployment", "my-deployment"},
+	}
+	for _, tt := range tests {
+		if got := baseModelName(tt.name); got != tt.want {
+			t.Errorf("baseModelName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFineTunedModelsInheritBaseFamily(t *testing.T) {
+	tests := []struct {
+		name      string
+		tools     bool
+		reasoning bool
+		vision    bool
+	}{
+		{name: "ft:gpt-4o-mini-2024-07-18:contoso::9abc", tools: true, vision: true},
+		{name: "gpt-4.1-2025-04-14.ft-0e208cf3", tools: true, vision: true},
+		{name: "gpt-35-turbo-0125-ft-abc123", tools: true},
+		{name: "o4-mini-2025-04-16.ft-1234abcd", tools: true, reasoning: true},
+		{name: "ft:o1-mini-2024-09-12:contoso::9abc", reasoning: true},
+	}
+	for _, tt := range tests {
+		family, ok := lookupModelFamily(tt.name)
+		if !ok {
+			t.Errorf("%s: no model family", tt.name)
+			continue
+		}
+		if family.tools != tt.tools || family.reasoning != tt.reasoning || family.vision != tt.vision {
+			t.Errorf("%s: tools=%v reasoning=%v vision=%v, want %v/%v/%v",
+				tt.name, family.tools, family.reasoning, family.vision, tt.tools, tt.reasoning, tt.vision)
+		}
+		if got := isReasoningModel(tt.name); got != tt.reasoning {
+			t.Errorf("isReasoningModel(%q) = %v, want %v", tt.name, got, tt.reasoning)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/packages/ssestream"
+)
+
+// OpenAIClient is the subset of the OpenAI SDK used for chat completions and embeddings.
+// Set AzureAIFoundry.OpenAIClient to a fake implementation to test generation and
+// embedding without network access; by default the SDK client built in Init is used.
+// Streams for fakes can be built with ssestream.NewStream.
+type OpenAIClient interface {
+	NewChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error)
+	NewChatCompletionStreaming(ctx context.Context, params openai.ChatCompletionNewParams, opts ...option.RequestOption) *ssestream.Stream[openai.ChatCompletionChunk]
+	NewEmbedding(ctx context.Context, params openai.EmbeddingNewParams, opts ...option.RequestOption) (*openai.CreateEmbeddingResponse, error)
+}
+
+// sdkClient implements OpenAIClient with the OpenAI SDK client
+type sdkClient struct {
+	client *openai.Client
+}
+
+// NewChatCompletion creates a chat completion
+func (c sdkClient) NewChatCompletion(ctx context.Context, params openai.ChatCompletionNewParams, opts ...option.RequestOption) (*openai.ChatCompletion, error) {
+	return c.client.Chat.Completions.New(ctx, params, opts...)
+}
+
+// NewChatCompletionStreaming creates a streaming chat completion
+func (c sdkClient) NewChatCompletionStreaming(ctx context.Context, params openai.ChatCompletionNewParams, opts ...option.RequestOption) *ssestream.Stream[openai.ChatCompletionChunk] {
+	return c.client.Chat.Completions.NewStreaming(ctx, params, opts...)
+}
+
+// NewEmbedding creates embeddings
+func (c sdkClient) NewEmbedding(ctx context.Context, params openai.EmbeddingNewParams, opts ...option.RequestOption) (*openai.CreateEmbeddingResponse, error) {
+	return c.client.Embeddings.New(ctx, params, opts...)
+}
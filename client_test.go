@@ -0,0 +1,105 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// newFakePlugin returns an initialized plugin whose chat and embedding calls go to a fakeClient.
+// The endpoint is never contacted.
+func newFakePlugin(t *testing.T, reply string) (*AzureAIFoundry, *fakeClient) {
+	t.Helper()
+	client := &fakeClient{t: t, reply: reply}
+	a := &AzureAIFoundry{
+		Endpoint:     "https://contoso.invalid/",
+		APIKey:       "unused",
+		OpenAIClient: client,
+	}
+	a.Init(context.Background())
+	return a, client
+}
+
+func TestOpenAIClientReplacesSDK(t *testing.T) {
+	tests := []struct {
+		name      string
+		streaming bool
+		config    map[string]interface{}
+	}{
+		{name: "sync", config: map[string]interface{}{"temperature": 0.2}},
+		{name: "streaming", streaming: true, config: map[string]interface{}{"temperature": 0.2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, client := newFakePlugin(t, "Hello there")
+
+			var chunks []string
+			var cb func(context.Context, *ai.ModelResponseChunk) error
+			if tt.streaming {
+				cb = func(_ context.Context, c *ai.ModelResponseChunk) error {
+					chunks = append(chunks, c.Text())
+					return nil
+				}
+			}
+			resp, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewSystemTextMessage("Be brief."), ai.NewUserTextMessage("hi")},
+				Config:   tt.config,
+			}, cb)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.Text() != "Hello there" || resp.FinishReason != ai.FinishReasonStop {
+				t.Errorf("response = %q (%s), want %q (stop)", resp.Text(), resp.FinishReason, "Hello there")
+			}
+			if tt.streaming && len(chunks) != 2 {
+				t.Errorf("chunks = %q, want one per word", chunks)
+			}
+			if len(client.chatParams) != 1 {
+				t.Fatalf("fake received %d chat calls, want 1", len(client.chatParams))
+			}
+			params := client.chatParams[0]
+			if len(params.Messages) != 2 || params.Messages[0].OfSystem == nil || params.Messages[1].OfUser == nil {
+				t.Errorf("messages = %+v, want system then user", params.Messages)
+			}
+			if params.Temperature.Value != 0.2 {
+				t.Errorf("temperature = %v, want 0.2", params.Temperature.Value)
+			}
+		})
+	}
+}
+
+func TestOpenAIClientEmbeddings(t *testing.T) {
+	a, client := newFakePlugin(t, "")
+
+	resp, err := a.embed(context.Background(), "text-embedding-3-small", &ai.EmbedRequest{Input: docs(3)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, embedding := range resp.Embeddings {
+		if embedding.Embedding[0] != float32(i) {
+			t.Errorf("embeddings[%d] = %v, want [%d]", i, embedding.Embedding, i)
+		}
+	}
+	if len(client.embedParams) != 1 || len(client.embedParams[0].Input.OfArrayOfStrings) != 3 {
+		t.Errorf("fake received %+v, want one call with 3 inputs", client.embedParams)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// apiCompletions is the legacy Completions API used for models defined with Type "text"
+// (e.g. gpt-35-turbo-instruct deployments)
+const apiCompletions = "completions"
+
+// generateCompletion handles text generation through the legacy Completions API.
+// The conversation is flattened into a single prompt.
+func (a *AzureAIFoundry) generateCompletion(ctx context.Context, modelName string, input *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	config, err := a.extractConfigFromRequest(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config for model '%s': %w", modelName, err)
+	}
+
+	params := openai.CompletionNewParams{
+		Model: openai.CompletionNewParamsModel(modelName),
+		Prompt: openai.CompletionNewParamsPromptUnion{
+			OfString: openai.String(completionPrompt(input.Messages)),
+		},
+	}
+	if config.maxTokens != nil {
+		params.MaxTokens = openai.Int(*config.maxTokens)
+	}
+	if config.temperature != nil {
+		params.Temperature = openai.Float(*config.temperature)
+	}
+	if config.topP != nil {
+		params.TopP = openai.Float(*config.topP)
+	}
+	if config.frequencyPenalty != nil {
+		params.FrequencyPenalty = openai.Float(*config.frequencyPenalty)
+	}
+	if config.presencePenalty != nil {
+		params.PresencePenalty = openai.Float(*config.presencePenalty)
+	}
+	if config.seed != nil {
+		params.Seed = openai.Int(*config.seed)
+	}
+	if config.user != "" {
+		params.User = openai.String(config.user)
+	}
+	switch len(config.stopSequences) {
+	case 0:
+	case 1:
+		params.Stop = openai.CompletionNewParamsStopUnion{
+			OfString: openai.String(config.stopSequences[0]),
+		}
+	default:
+		params.Stop = openai.CompletionNewParamsStopUnion{
+			OfStringArray: config.stopSequences,
+		}
+	}
+
+	if cb != nil {
+		return a.generateCompletionStream(ctx, params, input, cb)
+	}
+
+	var resp *openai.Completion
+	var httpResp *http.Response
+	err = a.withRetry(ctx, func() error {
+		var err error
+		resp, err = a.client.Completions.New(ctx, params, option.WithResponseInto(&httpResp))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("completion failed for model '%s': %w", modelName, classifyError(err))
+	}
+
+	var text, finishReason string
+	if len(resp.Choices) > 0 {
+		text = resp.Choices[0].Text
+		finishReason = string(resp.Choices[0].FinishReason)
+	}
+	result := a.completionResponse(text, finishReason, convertUsage(resp.Usage), input)
+	setServedBy(result, httpResp)
+	a.setRawResponse(result, resp)
+	return result, nil
+}
+
+// generateCompletionStream streams a legacy Completions API call
+func (a *AzureAIFoundry) generateCompletionStream(ctx context.Context, params openai.CompletionNewParams, input *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
+		IncludeUsage: openai.Bool(true),
+	}
+
+	// A stop from WithStreamStop cancels only this request, leaving ctx intact
+	reqCtx, cancelReq := context.WithCancel(ctx)
+	defer cancelReq()
+	if signal := streamStopSignal(ctx); signal != nil {
+		defer context.AfterFunc(signal, cancelReq)()
+	}
+
+	var httpResp *http.Response
+	stream := a.client.Completions.NewStreaming(reqCtx, params, option.WithResponseInto(&httpResp))
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	var text strings.Builder
+	var finishReason string
+	usage := &ai.GenerationUsage{}
+	for stream.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if streamStopped(ctx) {
+			break
+		}
+
+		chunk := stream.Current()
+		if chunk.Usage.TotalTokens > 0 {
+			usage = convertUsage(chunk.Usage)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			finishReason = string(choice.FinishReason)
+		}
+		if choice.Text != "" {
+			text.WriteString(choice.Text)
+			if err := cb(ctx, &ai.ModelResponseChunk{
+				Content: []*ai.Part{ai.NewTextPart(choice.Text)},
+			}); err != nil {
+				return nil, fmt.Errorf("streaming callback error: %w", err)
+			}
+		}
+	}
+	stopped := streamStopped(ctx)
+	if err := stream.Err(); err != nil && !stopped {
+		return nil, fmt.Errorf("stream error: %w", classifyError(err))
+	}
+
+	result := a.completionResponse(text.String(), finishReason, usage, input)
+	if stopped {
+		result.FinishReason = ai.FinishReasonInterrupted
+		result.FinishMessage = "stopped"
+	}
+	setServedBy(result, httpResp)
+	return result, nil
+}
+
+// completionResponse builds a Genkit response from a legacy completion
+func (a *AzureAIFoundry) completionResponse(text, finishReason string, usage *ai.GenerationUsage, input *ai.ModelRequest) *ai.ModelResponse {
+	var content []*ai.Part
+	if text = a.trimResultText(text, input); text != "" {
+		content = append(content, ai.NewTextPart(text))
+	}
+	return &ai.ModelResponse{
+		Message: &ai.Message{
+			Role:    ai.RoleModel,
+			Content: content,
+		},
+		FinishReason: convertFinishReason(finishReason),
+		Usage:        usage,
+	}
+}
+
+// completionPrompt flattens a conversation into a single prompt, one message per paragraph
+func completionPrompt(messages []*ai.Message) string {
+	var parts []string
+	for _, msg := range messages {
+		var text strings.Builder
+		for _, part := range msg.Content {
+			if part.IsText() {
+				text.WriteString(part.Text)
+			}
+		}
+		if text.Len() > 0 {
+			parts = append(parts, text.String())
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
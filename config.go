@@ -0,0 +1,93 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import "github.com/firebase/genkit/go/ai"
+
+// GenerationConfig is a typed alternative to a map[string]interface{} config for chat models.
+// Pass it (or a pointer to it) with ai.WithConfig; unset fields keep the Azure defaults.
+//
+//	ai.WithConfig(&azureaifoundry.GenerationConfig{
+//		MaxOutputTokens: 500,
+//		StopSequences:   []string{"END"},
+//	})
+type GenerationConfig struct {
+	Temperature          *float64          `json:"temperature,omitempty"`          // Sampling temperature (0 to 2); ignored by reasoning models
+	TopP                 *float64          `json:"topP,omitempty"`                 // Nucleus sampling probability mass (0 to 1); ignored by reasoning models
+	MaxOutputTokens      int               `json:"maxOutputTokens,omitempty"`      // Maximum tokens to generate
+	StopSequences        []string          `json:"stopSequences,omitempty"`        // Up to 4 sequences that end generation
+	FrequencyPenalty     *float64          `json:"frequencyPenalty,omitempty"`     // Penalty for frequent tokens (-2.0 to 2.0)
+	PresencePenalty      *float64          `json:"presencePenalty,omitempty"`      // Penalty for tokens already present (-2.0 to 2.0)
+	Seed                 *int64            `json:"seed,omitempty"`                 // Seed for best-effort deterministic sampling
+	ReasoningEffort      string            `json:"reasoningEffort,omitempty"`      // Reasoning models: "minimal", "low", "medium" or "high"
+	ToolChoice           string            `json:"toolChoice,omitempty"`           // "auto", "required", "none" or "function:<name>" to force a tool
+	ParallelToolCalls    *bool             `json:"parallelToolCalls,omitempty"`    // Allow several tool calls in one turn (Azure default when unset)
+	N                    int               `json:"n,omitempty"`                    // Number of completions to generate; see Candidates
+	Logprobs             bool              `json:"logprobs,omitempty"`             // Return per-token log probabilities
+	TopLogprobs          *int              `json:"topLogprobs,omitempty"`          // Most likely alternatives per token (0 to 20)
+	User                 string            `json:"user,omitempty"`                 // End-user identifier for abuse monitoring
+	API                  string            `json:"api,omitempty"`                  // "chat" or "responses", overriding the model default
+	Timeout              string            `json:"timeout,omitempty"`              // Call timeout as a duration string (e.g. "30s"), overriding RequestTimeout
+	Headers              map[string]string `json:"headers,omitempty"`              // Extra HTTP headers for this call
+	ConversationID       string            `json:"conversationId,omitempty"`       // Logical conversation ID for Responses API models
+	Audio                *AudioOutput      `json:"audio,omitempty"`                // Request spoken output from audio-capable models
+	LogitBias            map[int]int       `json:"logitBias,omitempty"`            // Token ID to bias (-100 to 100); ignored by reasoning models
+	PromptCacheKey       string            `json:"promptCacheKey,omitempty"`       // Routes requests sharing a long prefix to the same prompt cache
+	PromptCacheRetention string            `json:"promptCacheRetention,omitempty"` // Prompt cache retention: "in-memory" or "24h"
+	ServiceTier          string            `json:"serviceTier,omitempty"`          // Processing tier: "auto", "default", "flex", "scale" or "priority"
+	Store                *bool             `json:"store,omitempty"`                // Keep the completion in Azure stored completions
+	Metadata             map[string]string `json:"metadata,omitempty"`             // Tags for stored completions (up to 16 pairs)
+}
+
+// AudioOutput requests spoken output alongside text from audio-capable chat models
+type AudioOutput struct {
+	Voice  string `json:"voice"`            // Voice, e.g. "alloy", "echo", "shimmer"
+	Format string `json:"format,omitempty"` // "wav" (default), "mp3", "aac", "flac", "opus" or "pcm16"
+}
+
+// withConfigMap returns the request with a typed config converted to its map form, so that the
+// many requestConfig lookups made while serving it don't each repeat the JSON round-trip. The
+// input request is not modified.
+func withConfigMap(input *ai.ModelRequest) *ai.ModelRequest {
+	if _, isMap := input.Config.(map[string]interface{}); isMap || input.Config == nil {
+		return input
+	}
+	configMap, ok := requestConfig(input.Config)
+	if !ok {
+		return input
+	}
+	converted := *input
+	converted.Config = configMap
+	return &converted
+}
+
+// requestConfig returns a request config as a map. Maps are returned as is; typed configs such as
+// GenerationConfig or ai.GenerationCommonConfig are converted through their JSON field names.
+func requestConfig(config any) (map[string]interface{}, bool) {
+	switch c := config.(type) {
+	case nil:
+		return nil, false
+	case map[string]interface{}:
+		return c, true
+	}
+
+	var m map[string]interface{}
+	if err := cloneJSON(config, &m); err != nil || m == nil {
+		return nil, false
+	}
+	return m, true
+}
@@ -0,0 +1,200 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestWithConfigMap(t *testing.T) {
+	temperature := 0.3
+	typed := &GenerationConfig{Temperature: &temperature, StopSequences: []string{"END"}}
+	input := &ai.ModelRequest{Config: typed}
+
+	converted := withConfigMap(input)
+	want := map[string]interface{}{"temperature": 0.3, "stopSequences": []interface{}{"END"}}
+	if !reflect.DeepEqual(converted.Config, want) {
+		t.Errorf("converted config = %v, want %v", converted.Config, want)
+	}
+	if input.Config != typed {
+		t.Errorf("input config replaced")
+	}
+
+	mapped := &ai.ModelRequest{Config: want}
+	if withConfigMap(mapped) != mapped {
+		t.Errorf("map config converted again")
+	}
+}
+
+// BenchmarkGenerateConfig compares a chat request with a map config against the same request
+// with a typed config, whose conversion to a map is done once per request
+func TestSamplingParameterRanges(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   any
+		want    float64
+		wantErr bool
+	}{
+		{key: "temperature", value: 0.0, want: 0},
+		{key: "temperature", value: 2.0, want: 2},
+		{key: "temperature", value: 1, want: 1},
+		{key: "temperature", value: float32(0.5), want: 0.5},
+		{key: "temperature", value: json.Number("1.5"), want: 1.5},
+		{key: "temperature", value: -0.01, wantErr: true},
+		{key: "temperature", value: 2.01, wantErr: true},
+		{key: "temperature", value: math.NaN(), wantErr: true},
+		{key: "temperature", value: json.Number("hot"), wantErr: true},
+		{key: "temperature", value: "0.5", wantErr: true},
+		{key: "topP", value: 0.0, want: 0},
+		{key: "topP", value: 1.0, want: 1},
+		{key: "topP", value: 1, want: 1},
+		{key: "topP", value: json.Number("0.9"), want: 0.9},
+		{key: "topP", value: -0.1, wantErr: true},
+		{key: "topP", value: 1.01, wantErr: true},
+		{key: "topP", value: 2, wantErr: true},
+	}
+
+	a := &AzureAIFoundry{}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s=%v", tt.key, tt.value), func(t *testing.T) {
+			config, err := a.extractConfigFromRequest(&ai.ModelRequest{Config: map[string]any{tt.key: tt.value}})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("accepted out-of-range or non-numeric value")
+				}
+				if !strings.Contains(err.Error(), tt.key) {
+					t.Errorf("error %q does not name %s", err, tt.key)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := config.temperature
+			if tt.key == "topP" {
+				got = config.topP
+			}
+			if got == nil || *got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+// ctxKey marks a request context so log records can be traced back to it
+type ctxKey struct{}
+
+// ctxHandler records the ctxKey value of the context of every log record
+type ctxHandler struct {
+	slog.Handler
+	values *[]any
+}
+
+func (h ctxHandler) Handle(ctx context.Context, r slog.Record) error {
+	*h.values = append(*h.values, ctx.Value(ctxKey{}))
+	return nil
+}
+
+func TestMaxTokensClampPerDefinedModel(t *testing.T) {
+	var sent []any
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, decodeRequest(t, r)["max_tokens"])
+		writeChatCompletion(w, "ok")
+	})
+	var logged []any
+	g, a := newTestGenkit(t, server, func(a *AzureAIFoundry) {
+		a.Logger = slog.New(ctxHandler{slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}), &logged})
+	})
+	// Two aliases of one deployment: only the first caps its output
+	a.DefineModel(g, ModelDefinition{Name: "gpt-4o", Type: "chat", MaxTokens: 100}, nil, WithName("short"))
+	a.DefineModel(g, ModelDefinition{Name: "gpt-4o", Type: "chat"}, nil, WithName("long"))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-1")
+	for _, name := range []string{"short", "long"} {
+		if _, err := a.lookupModel(name)(ctx, &ai.ModelRequest{
+			Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+			Config:   map[string]interface{}{"maxOutputTokens": 500},
+		}, nil); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	if want := []any{100.0, 500.0}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("max_tokens sent = %v, want %v", sent, want)
+	}
+	// Every record, including the clamp's, is logged with the request context
+	if len(logged) != 3 {
+		t.Fatalf("logged %d records, want one clamp and two generations", len(logged))
+	}
+	for _, value := range logged {
+		if value != "request-1" {
+			t.Errorf("log record context value = %v, want the request's", value)
+		}
+	}
+}
+
+func BenchmarkGenerateConfig(b *testing.B) {
+	temperature, seed := 0.3, int64(7)
+	configs := map[string]any{
+		"map": map[string]interface{}{
+			"temperature":     temperature,
+			"seed":            seed,
+			"maxOutputTokens": 256,
+			"stopSequences":   []string{"END"},
+			"metadata":        map[string]string{"team": "search"},
+		},
+		"typed": &GenerationConfig{
+			Temperature:     &temperature,
+			Seed:            &seed,
+			MaxOutputTokens: 256,
+			StopSequences:   []string{"END"},
+			Metadata:        map[string]string{"team": "search"},
+		},
+	}
+
+	for _, name := range []string{"map", "typed"} {
+		b.Run(name, func(b *testing.B) {
+			a := &AzureAIFoundry{
+				Endpoint:         "http://localhost/v1/",
+				OpenAICompatible: true,
+				OpenAIClient:     &fakeClient{t: b, reply: "ok"},
+			}
+			a.Init(context.Background())
+			input := &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+				Config:   configs[name],
+			}
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, input, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// DatasetRecord is a captured (request, response) pair, e.g. for building eval or fine-tuning datasets
+type DatasetRecord struct {
+	Model     string            `json:"model"`     // Model deployment name
+	Timestamp time.Time         `json:"timestamp"` // When the generation completed
+	Request   *ai.ModelRequest  `json:"request"`   // Messages, config and tools sent
+	Response  *ai.ModelResponse `json:"response"`  // Final output, finish reason and usage
+}
+
+// DatasetSink receives a record after each successful chat generation.
+// Implementations must be safe for concurrent use and should not block for long.
+type DatasetSink interface {
+	Record(ctx context.Context, record *DatasetRecord)
+}
+
+// DatasetSinkFunc adapts a function to the DatasetSink interface
+type DatasetSinkFunc func(ctx context.Context, record *DatasetRecord)
+
+// Record calls f(ctx, record)
+func (f DatasetSinkFunc) Record(ctx context.Context, record *DatasetRecord) {
+	f(ctx, record)
+}
+
+// recordDataset sends a copy of the request/response pair to the configured sink,
+// applying the redaction hook to every text part
+func (a *AzureAIFoundry) recordDataset(ctx context.Context, modelName string, input *ai.ModelRequest, resp *ai.ModelResponse) {
+	if a.DatasetSink == nil {
+		return
+	}
+
+	// Work on copies so redaction never alters what the caller sees
+	var request *ai.ModelRequest
+	var response *ai.ModelResponse
+	if err := cloneJSON(input, &request); err != nil {
+		return
+	}
+	if err := cloneJSON(resp, &response); err != nil {
+		return
+	}
+
+	if a.DatasetRedactor != nil {
+		for _, msg := range request.Messages {
+			redactMessage(msg, a.DatasetRedactor)
+		}
+		redactMessage(response.Message, a.DatasetRedactor)
+	}
+
+	a.DatasetSink.Record(ctx, &DatasetRecord{
+		Model:     modelName,
+		Timestamp: time.Now(),
+		Request:   request,
+		Response:  response,
+	})
+}
+
+// redactMessage applies a redaction function to the text parts of a message
+func redactMessage(msg *ai.Message, redact func(string) string) {
+	if msg == nil {
+		return
+	}
+	for _, part := range msg.Content {
+		if part.IsText() {
+			part.Text = redact(part.Text)
+		}
+	}
+}
+
+// cloneJSON deep-copies src into dst through a JSON round trip
+func cloneJSON(src, dst any) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestDatasetSinkReceivesRecord(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeChatCompletion(w, "Call me at 555-0100")
+	})
+
+	var mu sync.Mutex
+	var records []*DatasetRecord
+	a := newTestPlugin(t, server, func(a *AzureAIFoundry) {
+		a.DatasetSink = DatasetSinkFunc(func(ctx context.Context, record *DatasetRecord) {
+			mu.Lock()
+			defer mu.Unlock()
+			records = append(records, record)
+		})
+		a.DatasetRedactor = func(text string) string {
+			return strings.ReplaceAll(text, "555-0100", "[phone]")
+		}
+	})
+
+	input := &ai.ModelRequest{
+		Messages: []*ai.Message{
+			ai.NewSystemTextMessage("You are a receptionist."),
+			ai.NewUserTextMessage("My number is 555-0100"),
+		},
+		Config: map[string]interface{}{"temperature": 0.2},
+	}
+	resp, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, input, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("sink received %d records, want 1", len(records))
+	}
+	record := records[0]
+	if record.Model != "gpt-4o" {
+		t.Errorf("Model = %q", record.Model)
+	}
+	if record.Timestamp.IsZero() {
+		t.Error("Timestamp not set")
+	}
+	if len(record.Request.Messages) != 2 || record.Request.Messages[1].Text() != "My number is [phone]" {
+		t.Errorf("request messages not recorded redacted: %v", record.Request.Messages)
+	}
+	if config, _ := record.Request.Config.(map[string]any); config["temperature"] != 0.2 {
+		t.Errorf("request config = %v", record.Request.Config)
+	}
+	if record.Response.Text() != "Call me at [phone]" {
+		t.Errorf("response text = %q", record.Response.Text())
+	}
+	if record.Response.FinishReason != ai.FinishReasonStop || record.Response.Usage.TotalTokens != 7 {
+		t.Errorf("response finish reason %q, usage %+v", record.Response.FinishReason, record.Response.Usage)
+	}
+	if _, err := json.Marshal(record); err != nil {
+		t.Errorf("record does not encode as JSON: %v", err)
+	}
+
+	// Redaction applies to the record only
+	if resp.Text() != "Call me at 555-0100" || input.Messages[1].Text() != "My number is 555-0100" {
+		t.Errorf("redaction leaked into the caller's request or response")
+	}
+}
+
+func TestDatasetSinkSkipsFailures(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request", "bad request")
+	})
+	called := false
+	a := newTestPlugin(t, server, func(a *AzureAIFoundry) {
+		a.DatasetSink = DatasetSinkFunc(func(context.Context, *DatasetRecord) { called = true })
+	})
+
+	if _, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	if called {
+		t.Error("sink called for a failed generation")
+	}
+}
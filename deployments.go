@@ -0,0 +1,148 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// deploymentsAPIVersion is the last data-plane API version that can list deployments
+const deploymentsAPIVersion = "2022-12-01"
+
+// DeploymentInfo describes a model deployment in the Azure OpenAI resource
+type DeploymentInfo struct {
+	Name         string                 // Deployment name, as passed to DefineModel
+	Model        string                 // Underlying model (e.g. "gpt-4o")
+	Family       string                 // Known model family prefix (e.g. "gpt-4o"), empty if unknown
+	Status       string                 // Provisioning status (e.g. "succeeded")
+	Capabilities DeploymentCapabilities // What the underlying model can be used for
+}
+
+// DeploymentCapabilities lists the APIs a deployment's model supports
+type DeploymentCapabilities struct {
+	ChatCompletion bool `json:"chat_completion"`
+	Completion     bool `json:"completion"`
+	Embeddings     bool `json:"embeddings"`
+	FineTune       bool `json:"fine_tune"`
+	Inference      bool `json:"inference"`
+}
+
+// ListDeployments returns the model deployments of the configured Azure OpenAI resource.
+// Capabilities come from the resource's models list; they are left empty when it is unavailable.
+func (a *AzureAIFoundry) ListDeployments(ctx context.Context) ([]DeploymentInfo, error) {
+	a.mu.Lock()
+	if !a.initted {
+		a.mu.Unlock()
+		return nil, ErrNotInitialized
+	}
+	client := a.client
+	a.mu.Unlock()
+
+	var deployments struct {
+		Data []struct {
+			ID     string `json:"id"`
+			Model  string `json:"model"`
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	err := client.Get(ctx, "deployments", nil, &deployments, option.WithQuery("api-version", deploymentsAPIVersion))
+	if err != nil {
+		return nil, fmt.Errorf("listing deployments failed: %w", classifyError(err))
+	}
+
+	var models struct {
+		Data []struct {
+			ID           string                 `json:"id"`
+			Capabilities DeploymentCapabilities `json:"capabilities"`
+		} `json:"data"`
+	}
+	capabilities := make(map[string]DeploymentCapabilities)
+	if err := client.Get(ctx, "models", nil, &models); err == nil {
+		for _, model := range models.Data {
+			capabilities[model.ID] = model.Capabilities
+		}
+	}
+
+	infos := make([]DeploymentInfo, 0, len(deployments.Data))
+	for _, d := range deployments.Data {
+		info := DeploymentInfo{
+			Name:         d.ID,
+			Model:        d.Model,
+			Status:       d.Status,
+			Capabilities: capabilities[d.Model],
+		}
+		if family, ok := lookupModelFamily(d.Model); ok {
+			info.Family = family.prefix
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// DefineModelsFromDeployments lists the resource's deployments and defines a model for each
+// chat or completions deployment, with capabilities inferred from the underlying model.
+// Deployments that are already defined, not yet provisioned, or rejected by filter (if non-nil)
+// are skipped. The returned map is keyed by deployment name.
+func DefineModelsFromDeployments(ctx context.Context, a *AzureAIFoundry, g *genkit.Genkit, filter func(DeploymentInfo) bool) (map[string]ai.Model, error) {
+	deployments, err := a.ListDeployments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make(map[string]ai.Model)
+	for _, d := range deployments {
+		if d.Status != "" && d.Status != "succeeded" {
+			continue
+		}
+		if IsDefinedModel(g, d.Name) {
+			continue
+		}
+		if filter != nil && !filter(d) {
+			continue
+		}
+
+		family, known := lookupModelFamily(d.Model)
+		definition := ModelDefinition{
+			Name:          d.Name,
+			SupportsMedia: known && family.vision,
+		}
+		switch {
+		case d.Capabilities.ChatCompletion:
+			definition.Type = "chat"
+		case d.Capabilities.Completion:
+			definition.Type = "text"
+		case known:
+			// Capabilities unavailable; known families are chat models
+			definition.Type = "chat"
+		default:
+			continue // Embedding, image, audio or unknown deployments
+		}
+
+		// Infer capabilities from the underlying model; deployment names are arbitrary
+		var info *ai.ModelInfo
+		if definition.Type == "chat" {
+			info = a.inferModelCapabilities(d.Model, definition.SupportsMedia)
+		}
+		models[d.Name] = a.DefineModel(g, definition, info)
+	}
+	return models, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// ErrEmptyDocument is returned by EmbedDocuments for a document without text, unless EmbedWithSkipEmpty is set
+var ErrEmptyDocument = errors.New("azureaifoundry: empty document")
+
+// EmbedOption customizes EmbedDocuments
+type EmbedOption func(*embedDocumentsOptions)
+
+// embedDocumentsOptions holds the settings collected from EmbedOptions
+type embedDocumentsOptions struct {
+	batchSize   int  // Documents per Embed call
+	concurrency int  // Embed calls in flight at once
+	skipEmpty   bool // Leave empty documents out instead of failing
+	options     any  // EmbedRequest.Options for every call
+}
+
+// EmbedWithBatchSize sets how many documents are sent per Embed call (default 16)
+func EmbedWithBatchSize(n int) EmbedOption {
+	return func(o *embedDocumentsOptions) {
+		o.batchSize = n
+	}
+}
+
+// EmbedWithConcurrency sets how many Embed calls may run at once (default 1)
+func EmbedWithConcurrency(n int) EmbedOption {
+	return func(o *embedDocumentsOptions) {
+		o.concurrency = n
+	}
+}
+
+// EmbedWithSkipEmpty leaves documents without text out of the request; their embeddings are nil
+func EmbedWithSkipEmpty() EmbedOption {
+	return func(o *embedDocumentsOptions) {
+		o.skipEmpty = true
+	}
+}
+
+// EmbedWithOptions sets the options of every embed request (e.g. {"dimensions": 256})
+func EmbedWithOptions(options any) EmbedOption {
+	return func(o *embedDocumentsOptions) {
+		o.options = options
+	}
+}
+
+// EmbedDocuments embeds docs with any Genkit embedder, split into batches that run concurrently.
+// The result lines up with docs: embeddings[i] belongs to docs[i]. Documents without text fail
+// with ErrEmptyDocument, or are left nil with EmbedWithSkipEmpty.
+//
+//	embeddings, err := azureaifoundry.EmbedDocuments(ctx, embedder, docs,
+//		azureaifoundry.EmbedWithBatchSize(64), azureaifoundry.EmbedWithConcurrency(4))
+func EmbedDocuments(ctx context.Context, embedder ai.Embedder, docs []*ai.Document, opts ...EmbedOption) ([]*ai.Embedding, error) {
+	options := embedDocumentsOptions{
+		batchSize:   defaultEmbeddingBatchSize,
+		concurrency: 1,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.batchSize = max(options.batchSize, 1)
+	options.concurrency = max(options.concurrency, 1)
+
+	// Positions of the documents to embed
+	var indices []int
+	for i, doc := range docs {
+		if doc == nil || documentText(doc) == "" {
+			if !options.skipEmpty {
+				return nil, fmt.Errorf("document %d: %w", i, ErrEmptyDocument)
+			}
+			continue
+		}
+		indices = append(indices, i)
+	}
+
+	var batches [][]int
+	for start := 0; start < len(indices); start += options.batchSize {
+		batches = append(batches, indices[start:min(start+options.batchSize, len(indices))])
+	}
+
+	embeddings := make([]*ai.Embedding, len(docs))
+	err := runBatches(ctx, len(batches), options.concurrency, func(ctx context.Context, b int) error {
+		batch := batches[b]
+		input := make([]*ai.Document, len(batch))
+		for i, idx := range batch {
+			input[i] = docs[idx]
+		}
+
+		resp, err := embedder.Embed(ctx, &ai.EmbedRequest{Input: input, Options: options.options})
+		if err == nil && len(resp.Embeddings) != len(batch) {
+			err = fmt.Errorf("embedder returned %d embeddings for %d documents", len(resp.Embeddings), len(batch))
+		}
+		if err != nil {
+			return fmt.Errorf("embedding documents %d-%d: %w", batch[0], batch[len(batch)-1], err)
+		}
+		for i, idx := range batch {
+			embeddings[idx] = resp.Embeddings[i]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings, nil
+}
+
+// documentText returns the concatenated text parts of a document
+func documentText(doc *ai.Document) string {
+	var text string
+	for _, part := range doc.Content {
+		if part.IsText() {
+			text += part.Text
+		}
+	}
+	return text
+}
+
+// runBatches calls fn for batches 0..n-1 on up to workers goroutines. The
+// first error cancels the context passed to the remaining calls and is
+// returned once every worker has stopped; batches cancelled because of it only
+// see context.Canceled, so their errors are discarded.
+func runBatches(ctx context.Context, n, workers int, fn func(ctx context.Context, i int) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var failOnce sync.Once
+	var firstErr error
+	for range max(min(workers, n), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := fn(ctx, i); err != nil {
+					failOnce.Do(func() {
+						firstErr = err
+						cancel() // Stop remaining batches early
+					})
+				}
+			}
+		}()
+	}
+	for i := range n {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return firstErr
+}
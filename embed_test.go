@@ -0,0 +1,308 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestEmbedBatchesRunConcurrentlyInOrder(t *testing.T) {
+	const concurrency = 4
+
+	var inFlight, peak atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body := decodeRequest(t, r)
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		inFlight.Add(-1)
+		writeEmbeddings(t, w, body)
+	})
+	a := newTestPlugin(t, server, nil)
+
+	resp, err := a.embed(context.Background(), "text-embedding-3-small", &ai.EmbedRequest{Input: docs(40)},
+		&EmbedderOptions{BatchSize: 2, Concurrency: concurrency})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Embeddings) != 40 {
+		t.Fatalf("got %d embeddings, want 40", len(resp.Embeddings))
+	}
+	for i, embedding := range resp.Embeddings {
+		if len(embedding.Embedding) != 1 || embedding.Embedding[0] != float32(i) {
+			t.Errorf("embeddings[%d] = %v, want [%d]", i, embedding.Embedding, i)
+		}
+	}
+	if p := peak.Load(); p < 2 || p > concurrency {
+		t.Errorf("peak concurrency = %d, want between 2 and %d", p, concurrency)
+	}
+}
+
+func TestEmbedReturnsFirstFailure(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body := decodeRequest(t, r)
+		if body["input"].([]any)[0] == "doc 3" {
+			writeAPIError(w, http.StatusTooManyRequests, "429", "rate limited")
+			return
+		}
+		// Earlier batches are still running when the last one fails, and get cancelled
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+			writeEmbeddings(t, w, body)
+		}
+	})
+	a := newTestPlugin(t, server, nil)
+
+	_, err := a.embed(context.Background(), "text-embedding-3-small", &ai.EmbedRequest{Input: docs(4)},
+		&EmbedderOptions{BatchSize: 1, Concurrency: 4})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("error = %v, want the rate limit failure", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, reports a batch cancelled because of the failure", err)
+	}
+}
+
+func TestEmbedDocumentsReturnsFirstFailure(t *testing.T) {
+	errFailed := errors.New("embedding failed")
+	embedder := ai.NewEmbedder("test/embedder", nil, func(ctx context.Context, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
+		if documentText(req.Input[0]) == "doc 3" {
+			return nil, errFailed
+		}
+		// Earlier batches are still running when the last one fails, and get cancelled
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	_, err := EmbedDocuments(context.Background(), embedder, docs(4), EmbedWithBatchSize(1), EmbedWithConcurrency(4))
+	if !errors.Is(err, errFailed) {
+		t.Errorf("error = %v, want the batch failure", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, reports a batch cancelled because of the failure", err)
+	}
+}
+
+func TestRunBatchesBoundsWorkersAndCancelsOnFailure(t *testing.T) {
+	var running, peak, calls atomic.Int32
+	boom := errors.New("boom")
+	err := runBatches(context.Background(), 20, 3, func(ctx context.Context, i int) error {
+		calls.Add(1)
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		if i == 2 {
+			return boom
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(20 * time.Millisecond):
+			return nil
+		}
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want the first failure", err)
+	}
+	if p := peak.Load(); p > 3 {
+		t.Errorf("%d batches ran at once, want at most 3", p)
+	}
+	if c := calls.Load(); c != 20 {
+		t.Errorf("fn called %d times, want every batch handed out", c)
+	}
+}
+
+func TestEmbedDocumentsSkipsEmptyInOrder(t *testing.T) {
+	embedder := ai.NewEmbedder("test/embedder", nil, func(_ context.Context, req *ai.EmbedRequest) (*ai.EmbedResponse, error) {
+		resp := &ai.EmbedResponse{}
+		for _, doc := range req.Input {
+			var n int
+			if _, err := fmt.Sscanf(documentText(doc), "doc %d", &n); err != nil {
+				return nil, err
+			}
+			resp.Embeddings = append(resp.Embeddings, &ai.Embedding{Embedding: []float32{float32(n)}})
+		}
+		return resp, nil
+	})
+	input := docs(5)
+	input[1] = ai.DocumentFromText("", nil)
+
+	if _, err := EmbedDocuments(context.Background(), embedder, input); !errors.Is(err, ErrEmptyDocument) {
+		t.Errorf("error = %v, want ErrEmptyDocument", err)
+	}
+
+	embeddings, err := EmbedDocuments(context.Background(), embedder, input,
+		EmbedWithBatchSize(2), EmbedWithConcurrency(2), EmbedWithSkipEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, embedding := range embeddings {
+		if i == 1 {
+			if embedding != nil {
+				t.Errorf("embeddings[1] = %v, want nil for the empty document", embedding.Embedding)
+			}
+			continue
+		}
+		if embedding == nil || embedding.Embedding[0] != float32(i) {
+			t.Errorf("embeddings[%d] = %v, want [%d]", i, embedding, i)
+		}
+	}
+}
+
+func TestEmbedDimensionsValidatedEarly(t *testing.T) {
+	tests := []struct {
+		model      string
+		dimensions any
+		wantErr    string // Empty when the request is sent
+	}{
+		{model: "text-embedding-3-small", dimensions: 512},
+		{model: "text-embedding-3-large", dimensions: 3072},
+		{model: "text-embedding-3-small", dimensions: 3072, wantErr: "supports at most 1536 dimensions"},
+		{model: "text-embedding-ada-002", dimensions: 3000, wantErr: "fixed dimension of 1536"},
+		{model: "text-embedding-ada-002", dimensions: 1536, wantErr: "fixed dimension of 1536"},
+		{model: "my-embedder", dimensions: 256, wantErr: "does not support the dimensions option"},
+		{model: "text-embedding-3-small", dimensions: 0, wantErr: "positive integer"},
+		{model: "text-embedding-3-small", dimensions: "512", wantErr: "positive integer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%#v", tt.model, tt.dimensions), func(t *testing.T) {
+			var sent any
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				body := decodeRequest(t, r)
+				sent = body["dimensions"]
+				writeEmbeddings(t, w, body)
+			})
+			a := newTestPlugin(t, server, nil)
+
+			_, err := a.embed(context.Background(), tt.model, &ai.EmbedRequest{
+				Input:   docs(1),
+				Options: map[string]interface{}{"dimensions": tt.dimensions},
+			}, nil)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if sent != float64(tt.dimensions.(int)) {
+					t.Errorf("dimensions sent = %v, want %v", sent, tt.dimensions)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+			if sent != nil {
+				t.Errorf("request sent with dimensions %v, want none", sent)
+			}
+		})
+	}
+}
+
+// BenchmarkEmbedEncoding compares embedding a batch of 16 documents with 1536-dimension float
+// vectors against the same vectors base64-encoded, reporting the response size of each
+func BenchmarkEmbedEncoding(b *testing.B) {
+	const (
+		inputs     = 16
+		dimensions = 1536
+	)
+
+	vector := make([]float32, dimensions)
+	for i := range vector {
+		vector[i] = float32(math.Sin(float64(i))) / 10
+	}
+	raw := make([]byte, 4*dimensions)
+	for i, x := range vector {
+		binary.LittleEndian.PutUint32(raw[4*i:], math.Float32bits(x))
+	}
+
+	bodies := make(map[string][]byte)
+	for encoding, embedding := range map[string]any{"float": vector, "base64": base64.StdEncoding.EncodeToString(raw)} {
+		data := make([]any, inputs)
+		for i := range data {
+			data[i] = map[string]any{"object": "embedding", "index": i, "embedding": embedding}
+		}
+		body, err := json.Marshal(map[string]any{
+			"object": "list",
+			"model":  "text-embedding-3-small",
+			"data":   data,
+			"usage":  map[string]any{"prompt_tokens": inputs, "total_tokens": inputs},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		bodies[encoding] = body
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			EncodingFormat string `json:"encoding_format"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(bodies[cmp.Or(body.EncodingFormat, "float")])
+	}))
+	defer server.Close()
+
+	a := testPlugin(server, nil)
+	a.Init(context.Background())
+	req := &ai.EmbedRequest{Input: docs(inputs)}
+
+	for _, encoding := range []string{"float", "base64"} {
+		b.Run(encoding, func(b *testing.B) {
+			opts := &EmbedderOptions{BatchSize: inputs, Base64: encoding == "base64"}
+			b.ReportAllocs()
+			b.ReportMetric(float64(len(bodies[encoding])), "resp-bytes")
+			for b.Loop() {
+				resp, err := a.embed(context.Background(), "text-embedding-3-small", req, opts)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if got := resp.Embeddings[0].Embedding[1]; got != vector[1] {
+					b.Fatalf("embedding[1] = %v, want %v", got, vector[1])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// normalizeEndpoint validates an Azure endpoint and reduces it to the resource root
+// (https://<resource>.openai.azure.com/). Paths such as "/openai", "/openai/v1" or a full
+// deployment URL are stripped, since the SDK adds the "/openai/" prefix itself.
+func normalizeEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", fmt.Errorf("invalid Endpoint %q: %w", endpoint, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid Endpoint %q: must be an absolute URL such as https://<resource>.openai.azure.com/", endpoint)
+	}
+	if u.Scheme != "https" {
+		return "", fmt.Errorf("invalid Endpoint %q: Azure endpoints must use https", endpoint)
+	}
+
+	if idx := strings.Index(u.Path, "/openai"); idx >= 0 {
+		u.Path = u.Path[:idx]
+	}
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	return strings.TrimSuffix(u.String(), "/") + "/", nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		want     string
+		wantErr  string
+	}{
+		{name: "resource root", endpoint: "https://contoso.openai.azure.com/", want: "https://contoso.openai.azure.com/"},
+		{name: "missing trailing slash", endpoint: "https://contoso.openai.azure.com", want: "https://contoso.openai.azure.com/"},
+		{name: "surrounding whitespace", endpoint: "  https://contoso.openai.azure.com/ ", want: "https://contoso.openai.azure.com/"},
+		{name: "openai path", endpoint: "https://contoso.openai.azure.com/openai", want: "https://contoso.openai.azure.com/"},
+		{name: "openai v1 path", endpoint: "https://contoso.openai.azure.com/openai/v1/", want: "https://contoso.openai.azure.com/"},
+		{
+			name:     "full deployment URL",
+			endpoint: "https://contoso.openai.azure.com/openai/deployments/gpt-4o/chat/completions?api-version=2024-10-21",
+			want:     "https://contoso.openai.azure.com/",
+		},
+		{name: "AI Foundry project host", endpoint: "https://contoso.services.ai.azure.com/openai/v1", want: "https://contoso.services.ai.azure.com/"},
+		{name: "missing scheme", endpoint: "contoso.openai.azure.com", wantErr: "must be an absolute URL"},
+		{name: "http", endpoint: "http://contoso.openai.azure.com/", wantErr: "must use https"},
+		{name: "empty", endpoint: "", wantErr: "must be an absolute URL"},
+		{name: "unparsable", endpoint: "https://contoso .openai.azure.com/", wantErr: "invalid Endpoint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeEndpoint(tt.endpoint)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("normalizeEndpoint(%q) error = %v, want %q", tt.endpoint, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeEndpoint(%q) = %q, want %q", tt.endpoint, got, tt.want)
+			}
+		})
+	}
+}
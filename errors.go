@@ -0,0 +1,107 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/openai/openai-go/v3"
+)
+
+// Sentinel errors for common Azure failures. Match them with errors.Is; use errors.As with
+// *APIError for the HTTP status and Azure error code.
+var (
+	ErrRateLimited     = errors.New("azureaifoundry: rate limited")
+	ErrContentFiltered = errors.New("azureaifoundry: content filtered")
+	ErrAuthentication  = errors.New("azureaifoundry: authentication failed")
+	ErrModelNotFound   = errors.New("azureaifoundry: model deployment not found")
+)
+
+// ErrNotInitialized is returned by calls made before Init, or on a plugin whose client is
+// unusable, instead of dereferencing a zero client.
+var ErrNotInitialized = errors.New("azureaifoundry: client not initialized")
+
+// ErrUnsupportedRole is returned for a message whose role the plugin cannot send. Requests
+// fail with it rather than silently dropping the message.
+var ErrUnsupportedRole = errors.New("azureaifoundry: unsupported message role")
+
+// messageRole maps a message role to one of the four Genkit roles. OpenAI-style aliases
+// ("assistant", "developer", "function") are accepted; anything else is an error.
+func messageRole(role ai.Role) (ai.Role, error) {
+	switch role {
+	case ai.RoleSystem, ai.RoleUser, ai.RoleModel, ai.RoleTool:
+		return role, nil
+	case "assistant":
+		return ai.RoleModel, nil
+	case "developer":
+		return ai.RoleSystem, nil
+	case "function":
+		return ai.RoleTool, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedRole, role)
+	}
+}
+
+// APIError is a classified Azure API failure. It unwraps to the matching sentinel error
+// (if any) and to the underlying *openai.Error.
+type APIError struct {
+	StatusCode int    // HTTP status code
+	Code       string // Azure error code (e.g. "content_filter", "DeploymentNotFound")
+	Kind       error  // Matching sentinel error, or nil when the failure is not classified
+	Err        error  // Underlying error
+}
+
+// Error returns the underlying error message
+func (e *APIError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap exposes both the sentinel and the underlying error to errors.Is and errors.As
+func (e *APIError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// classifyError wraps Azure API errors in an *APIError; other errors are returned unchanged
+func classifyError(err error) error {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	classified := &APIError{
+		StatusCode: apiErr.StatusCode,
+		Code:       apiErr.Code,
+		Err:        err,
+	}
+	switch {
+	case apiErr.Code == "content_filter" || apiErr.Code == "content_policy_violation":
+		classified.Kind = ErrContentFiltered
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		classified.Kind = ErrRateLimited
+	case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+		classified.Kind = ErrAuthentication
+	case apiErr.StatusCode == http.StatusNotFound || apiErr.Code == "DeploymentNotFound":
+		classified.Kind = ErrModelNotFound
+	}
+	return classified
+}
@@ -21,32 +21,12 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"math"
 
 	"github.com/firebase/genkit/go/ai"
+	azureaifoundry "github.com/xavidop/genkit-azure-foundry-go"
 	"github.com/xavidop/genkit-azure-foundry-go/examples/common"
 )
 
-// cosineSimilarity calculates the cosine similarity between two vectors
-func cosineSimilarity(a, b []float32) float64 {
-	if len(a) != len(b) {
-		return 0
-	}
-
-	var dotProduct, normA, normB float64
-	for i := range a {
-		dotProduct += float64(a[i]) * float64(b[i])
-		normA += float64(a[i]) * float64(a[i])
-		normB += float64(b[i]) * float64(b[i])
-	}
-
-	if normA == 0 || normB == 0 {
-		return 0
-	}
-
-	return dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
-}
-
 func main() {
 	ctx := context.Background()
 
@@ -69,35 +49,30 @@ func main() {
 		"Cloud computing enables scalable AI solutions.",
 	}
 
-	// Generate embeddings for all texts
-	var embeddings []*ai.Embedding
-	for _, text := range texts {
-		log.Printf("Generating embedding for: %s", text)
-
-		// Create embed request
-		embedRequest := &ai.EmbedRequest{
-			Input: []*ai.Document{
-				ai.DocumentFromText(text, nil),
-			},
-		}
-
-		// Call the embedder directly
-		embedResponse, err := embedder.Embed(ctx, embedRequest)
-		if err != nil {
-			log.Fatalf("Error generating embedding: %v", err)
-		}
-
-		if len(embedResponse.Embeddings) > 0 {
-			embeddings = append(embeddings, embedResponse.Embeddings[0])
-			log.Printf("✓ Generated embedding with dimension: %d", len(embedResponse.Embeddings[0].Embedding))
-		}
+	// Generate embeddings for all texts, batched and aligned with the input
+	docs := make([]*ai.Document, len(texts))
+	for i, text := range texts {
+		docs[i] = ai.DocumentFromText(text, nil)
+	}
+	embeddings, err := azureaifoundry.EmbedDocuments(ctx, embedder, docs,
+		azureaifoundry.EmbedWithBatchSize(2),
+		azureaifoundry.EmbedWithConcurrency(2),
+	)
+	if err != nil {
+		log.Fatalf("Error generating embeddings: %v", err)
+	}
+	for i, embedding := range embeddings {
+		log.Printf("✓ Generated embedding with dimension %d for: %s", len(embedding.Embedding), texts[i])
 	}
 
 	// Calculate and display similarities between texts
 	log.Println("\n=== Similarity Analysis ===")
 	for i := 0; i < len(texts); i++ {
 		for j := i + 1; j < len(texts); j++ {
-			similarity := cosineSimilarity(embeddings[i].Embedding, embeddings[j].Embedding)
+			similarity, err := azureaifoundry.CosineSimilarity(embeddings[i].Embedding, embeddings[j].Embedding)
+			if err != nil {
+				log.Fatalf("Failed to compare embeddings: %v", err)
+			}
 			log.Printf("Similarity between text %d and %d: %.4f", i+1, j+1, similarity)
 			log.Printf("  Text %d: %s", i+1, texts[i])
 			log.Printf("  Text %d: %s", j+1, texts[j])
@@ -110,7 +85,10 @@ func main() {
 	var maxI, maxJ int
 	for i := 0; i < len(texts); i++ {
 		for j := i + 1; j < len(texts); j++ {
-			similarity := cosineSimilarity(embeddings[i].Embedding, embeddings[j].Embedding)
+			similarity, err := azureaifoundry.CosineSimilarity(embeddings[i].Embedding, embeddings[j].Embedding)
+			if err != nil {
+				log.Fatalf("Failed to compare embeddings: %v", err)
+			}
 			if similarity > maxSim {
 				maxSim = similarity
 				maxI = i
@@ -0,0 +1,178 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// defaultFailoverCooldown is how long a failing endpoint is skipped when FailoverCooldown is not set
+const defaultFailoverCooldown = 30 * time.Second
+
+// FailoverEndpoint is a secondary Azure resource serving the same deployments as Endpoint,
+// e.g. in another region
+type FailoverEndpoint struct {
+	Endpoint string // Resource endpoint, e.g. https://my-resource-westus.openai.azure.com/
+	APIKey   string // API key for this resource; empty to reuse the primary's authentication
+}
+
+// failoverBackend is an endpoint in priority order with its health state
+type failoverBackend struct {
+	baseURL     string    // Normalized resource root, ending in "/"
+	apiKey      string    // Replacement API key, or empty to keep the request's authentication
+	unhealthyAt time.Time // Skip the backend until this time
+}
+
+// failover routes requests to the first healthy endpoint in priority order and moves on to
+// the next one when an endpoint fails with a retryable status or a network error
+type failover struct {
+	mu       sync.Mutex
+	backends []*failoverBackend
+	cooldown time.Duration
+}
+
+// newFailover builds the failover state for the primary endpoint followed by the secondaries
+func newFailover(primary string, secondaries []FailoverEndpoint, cooldown time.Duration) (*failover, error) {
+	if cooldown <= 0 {
+		cooldown = defaultFailoverCooldown
+	}
+	f := &failover{
+		backends: []*failoverBackend{{baseURL: primary}},
+		cooldown: cooldown,
+	}
+	for _, secondary := range secondaries {
+		baseURL, err := normalizeEndpoint(secondary.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		f.backends = append(f.backends, &failoverBackend{baseURL: baseURL, apiKey: secondary.APIKey})
+	}
+	return f, nil
+}
+
+// order returns the backends to try: healthy ones first, in priority order, then the ones
+// cooling down so a request is never refused outright
+func (f *failover) order(now time.Time) []*failoverBackend {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	healthy := make([]*failoverBackend, 0, len(f.backends))
+	var cooling []*failoverBackend
+	for _, b := range f.backends {
+		if now.Before(b.unhealthyAt) {
+			cooling = append(cooling, b)
+		} else {
+			healthy = append(healthy, b)
+		}
+	}
+	return append(healthy, cooling...)
+}
+
+// markUnhealthy skips a backend for the cooldown period
+func (f *failover) markUnhealthy(b *failoverBackend, now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b.unhealthyAt = now.Add(f.cooldown)
+}
+
+// markHealthy clears a backend's cooldown after a successful call
+func (f *failover) markHealthy(b *failoverBackend) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b.unhealthyAt = time.Time{}
+}
+
+// middleware sends a request to each backend in turn until one does not fail over
+func (f *failover) middleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	primary := f.backends[0].baseURL
+	target := req.URL.String()
+	if !strings.HasPrefix(target, primary) || (req.Body != nil && req.GetBody == nil) {
+		// Not an Azure resource call, or a body that cannot be replayed
+		return next(req)
+	}
+	path := strings.TrimPrefix(target, primary)
+
+	backends := f.order(time.Now())
+	for i, b := range backends {
+		attempt, err := b.request(req, path)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := next(attempt)
+		if !shouldFailover(req.Context(), resp, err) {
+			if err == nil {
+				f.markHealthy(b)
+			}
+			return resp, err
+		}
+		f.markUnhealthy(b, time.Now())
+		if i == len(backends)-1 {
+			return resp, err
+		}
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+	}
+	return next(req)
+}
+
+// request clones req for this backend, rewriting its URL and, if set, its API key
+func (b *failoverBackend) request(req *http.Request, path string) (*http.Request, error) {
+	u, err := url.Parse(b.baseURL + path)
+	if err != nil {
+		return nil, err
+	}
+	attempt := req.Clone(req.Context())
+	attempt.URL = u
+	attempt.Host = ""
+	if req.GetBody != nil {
+		if attempt.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	if b.apiKey != "" {
+		attempt.Header.Del("Authorization")
+		attempt.Header.Set("Api-Key", b.apiKey)
+	}
+	return attempt, nil
+}
+
+// shouldFailover reports whether a call failed in a way another region may not
+func shouldFailover(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// The caller giving up is not an endpoint failure
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+
+	"github.com/openai/openai-go/v3/option"
+)
+
+// requestHeadersKey is the context key for per-request HTTP headers
+type requestHeadersKey struct{}
+
+// WithRequestHeaders returns a context that adds the given HTTP headers to every Azure call
+// made with it (e.g. x-ms-client-request-id). Headers from an enclosing WithRequestHeaders
+// are kept unless overridden.
+func WithRequestHeaders(ctx context.Context, headers map[string]string) context.Context {
+	merged := make(map[string]string)
+	if existing, ok := ctx.Value(requestHeadersKey{}).(map[string]string); ok {
+		maps.Copy(merged, existing)
+	}
+	maps.Copy(merged, headers)
+	return context.WithValue(ctx, requestHeadersKey{}, merged)
+}
+
+// requestHeadersMiddleware sets the per-request headers from the context
+func requestHeadersMiddleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	if headers, ok := req.Context().Value(requestHeadersKey{}).(map[string]string); ok {
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+	}
+	return next(req)
+}
+
+// contextWithConfigHeaders applies the "headers" config key (a map of header names to
+// string values) to ctx
+func contextWithConfigHeaders(ctx context.Context, config any) (context.Context, error) {
+	configMap, ok := requestConfig(config)
+	if !ok {
+		return ctx, nil
+	}
+	raw, present := configMap["headers"]
+	if !present {
+		return ctx, nil
+	}
+
+	headers := make(map[string]string)
+	switch h := raw.(type) {
+	case map[string]string:
+		maps.Copy(headers, h)
+	case map[string]interface{}:
+		for key, value := range h {
+			str, ok := value.(string)
+			if !ok {
+				return ctx, fmt.Errorf("header %q must be a string, got %T", key, value)
+			}
+			headers[key] = str
+		}
+	default:
+		return ctx, fmt.Errorf("headers must be a map of strings, got %T", raw)
+	}
+	return WithRequestHeaders(ctx, headers), nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Health check failures not covered by the API error sentinels
+var (
+	ErrUnreachable      = errors.New("azureaifoundry: endpoint unreachable")
+	ErrEndpointNotFound = errors.New("azureaifoundry: endpoint or API version not found")
+)
+
+// HealthCheck verifies the endpoint, API version and credentials with a cheap models-list
+// request, without generating anything. Failures match ErrUnreachable (network),
+// ErrAuthentication (credentials) or ErrEndpointNotFound (wrong endpoint or API version).
+func (a *AzureAIFoundry) HealthCheck(ctx context.Context) error {
+	a.mu.Lock()
+	if !a.initted {
+		a.mu.Unlock()
+		return ErrNotInitialized
+	}
+	client := a.client
+	a.mu.Unlock()
+
+	_, err := client.Models.List(ctx)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var apiErr *APIError
+	if errors.As(classifyError(err), &apiErr) {
+		// A 404 on the models list means the endpoint or API version is wrong, not a deployment
+		if apiErr.StatusCode == http.StatusNotFound {
+			apiErr.Kind = ErrEndpointNotFound
+		}
+		return fmt.Errorf("health check failed: %w", apiErr)
+	}
+	return fmt.Errorf("health check failed: %w: %w", ErrUnreachable, err)
+}
@@ -0,0 +1,284 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/firebase/genkit/go/genkit"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/packages/ssestream"
+)
+
+// newTestServer starts a stub OpenAI-compatible server, closed when the test ends
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// testPlugin returns a plugin pointed at a stub server, with the SDK's own retries disabled so
+// tests see every attempt. configure, if set, adjusts it before Init.
+func testPlugin(server *httptest.Server, configure func(*AzureAIFoundry)) *AzureAIFoundry {
+	a := &AzureAIFoundry{
+		Endpoint:         server.URL + "/",
+		APIKey:           "test-key",
+		OpenAICompatible: true,
+		ExtraOptions:     []option.RequestOption{option.WithMaxRetries(0)},
+	}
+	if configure != nil {
+		configure(a)
+	}
+	return a
+}
+
+// newTestPlugin returns an initialized plugin pointed at a stub server
+func newTestPlugin(t *testing.T, server *httptest.Server, configure func(*AzureAIFoundry)) *AzureAIFoundry {
+	t.Helper()
+	a := testPlugin(server, configure)
+	a.Init(context.Background())
+	return a
+}
+
+// newTestGenkit initializes Genkit with a plugin pointed at a stub server
+func newTestGenkit(t *testing.T, server *httptest.Server, configure func(*AzureAIFoundry)) (*genkit.Genkit, *AzureAIFoundry) {
+	t.Helper()
+	a := testPlugin(server, configure)
+	return genkit.Init(context.Background(), genkit.WithPlugins(a)), a
+}
+
+// writeJSON writes v as a JSON response body with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// chatCompletion builds a single-choice chat completion with the given text
+func chatCompletion(text string) map[string]any {
+	return map[string]any{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion",
+		"created": 0,
+		"model":   "gpt-4o",
+		"choices": []any{map[string]any{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": text},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]any{"prompt_tokens": 5, "completion_tokens": 2, "total_tokens": 7},
+	}
+}
+
+// writeChatCompletion writes a single-choice chat completion with the given text
+func writeChatCompletion(w http.ResponseWriter, text string) {
+	writeJSON(w, http.StatusOK, chatCompletion(text))
+}
+
+// writeAPIError writes an OpenAI-style error response
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{"code": code, "message": message},
+	})
+}
+
+// streamChunk builds a chat completion chunk with one choice delta. An empty finishReason
+// leaves the choice unfinished.
+func streamChunk(delta map[string]any, finishReason string) map[string]any {
+	choice := map[string]any{"index": 0, "delta": delta, "finish_reason": nil}
+	if finishReason != "" {
+		choice["finish_reason"] = finishReason
+	}
+	return map[string]any{
+		"id":      "chatcmpl-test",
+		"object":  "chat.completion.chunk",
+		"created": 0,
+		"model":   "gpt-4o",
+		"choices": []any{choice},
+	}
+}
+
+// writeStream writes chunks as server-sent events, flushing each one, followed by [DONE]
+func writeStream(w http.ResponseWriter, chunks ...map[string]any) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	for _, chunk := range chunks {
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// textStream returns the chunks of a streamed answer made of the given text deltas
+func textStream(deltas ...string) []map[string]any {
+	chunks := make([]map[string]any, 0, len(deltas)+1)
+	for _, delta := range deltas {
+		chunks = append(chunks, streamChunk(map[string]any{"content": delta}, ""))
+	}
+	return append(chunks, streamChunk(map[string]any{}, "stop"))
+}
+
+// writeEvents writes Responses API events as server-sent events, named after their type
+func writeEvents(w http.ResponseWriter, events ...map[string]any) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	for i, event := range events {
+		event["sequence_number"] = i
+		data, _ := json.Marshal(event)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event["type"], data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// responsesResponse builds a completed Responses API response with the given output items
+func responsesResponse(output ...map[string]any) map[string]any {
+	return map[string]any{
+		"id":         "resp_test",
+		"object":     "response",
+		"created_at": 0,
+		"status":     "completed",
+		"model":      "o4-mini",
+		"output":     output,
+		"usage":      map[string]any{"input_tokens": 5, "output_tokens": 4, "total_tokens": 9},
+	}
+}
+
+// outputMessage builds a Responses API assistant message item with the given text
+func outputMessage(text string) map[string]any {
+	return map[string]any{
+		"type":    "message",
+		"id":      "msg_test",
+		"role":    "assistant",
+		"status":  "completed",
+		"content": []any{map[string]any{"type": "output_text", "text": text, "annotations": []any{}}},
+	}
+}
+
+// embeddings builds the response to an embeddings request made of "doc N" inputs: one-dimensional
+// vectors [N], so tests can check that every embedding lines up with its document
+func embeddings(t testing.TB, inputs []string) map[string]any {
+	t.Helper()
+	data := make([]any, len(inputs))
+	for i, input := range inputs {
+		var n int
+		if _, err := fmt.Sscanf(input, "doc %d", &n); err != nil {
+			t.Errorf("unexpected input %q", input)
+		}
+		data[i] = map[string]any{"object": "embedding", "index": i, "embedding": []float64{float64(n)}}
+	}
+	return map[string]any{
+		"object": "list",
+		"model":  "text-embedding-3-small",
+		"data":   data,
+		"usage":  map[string]any{"prompt_tokens": len(inputs), "total_tokens": len(inputs)},
+	}
+}
+
+// writeEmbeddings answers a decoded embeddings request (see embeddings)
+func writeEmbeddings(t *testing.T, w http.ResponseWriter, body map[string]any) {
+	t.Helper()
+	raw, _ := body["input"].([]any)
+	inputs := make([]string, len(raw))
+	for i, input := range raw {
+		inputs[i], _ = input.(string)
+	}
+	writeJSON(w, http.StatusOK, embeddings(t, inputs))
+}
+
+// fakeClient is an in-memory OpenAIClient. Chat completions answer reply, streamed as one
+// chunk per word; embeddings answer as writeEmbeddings does. It records the parameters it gets.
+type fakeClient struct {
+	t     testing.TB
+	reply string
+
+	mu          sync.Mutex
+	chatParams  []openai.ChatCompletionNewParams
+	embedParams []openai.EmbeddingNewParams
+}
+
+// NewChatCompletion answers with the reply
+func (c *fakeClient) NewChatCompletion(_ context.Context, params openai.ChatCompletionNewParams, _ ...option.RequestOption) (*openai.ChatCompletion, error) {
+	c.mu.Lock()
+	c.chatParams = append(c.chatParams, params)
+	c.mu.Unlock()
+
+	var completion openai.ChatCompletion
+	if err := cloneJSON(chatCompletion(c.reply), &completion); err != nil {
+		c.t.Fatal(err)
+	}
+	return &completion, nil
+}
+
+// NewChatCompletionStreaming streams the reply, one chunk per word
+func (c *fakeClient) NewChatCompletionStreaming(_ context.Context, params openai.ChatCompletionNewParams, _ ...option.RequestOption) *ssestream.Stream[openai.ChatCompletionChunk] {
+	c.mu.Lock()
+	c.chatParams = append(c.chatParams, params)
+	c.mu.Unlock()
+
+	recorder := httptest.NewRecorder()
+	writeStream(recorder, textStream(strings.SplitAfter(c.reply, " ")...)...)
+	return ssestream.NewStream[openai.ChatCompletionChunk](ssestream.NewDecoder(recorder.Result()), nil)
+}
+
+// NewEmbedding answers "doc N" inputs with vectors [N]
+func (c *fakeClient) NewEmbedding(_ context.Context, params openai.EmbeddingNewParams, _ ...option.RequestOption) (*openai.CreateEmbeddingResponse, error) {
+	c.mu.Lock()
+	c.embedParams = append(c.embedParams, params)
+	c.mu.Unlock()
+
+	var resp openai.CreateEmbeddingResponse
+	if err := cloneJSON(embeddings(c.t, params.Input.OfArrayOfStrings), &resp); err != nil {
+		c.t.Fatal(err)
+	}
+	return &resp, nil
+}
+
+// docs returns n text documents "doc 0" to "doc n-1"
+func docs(n int) []*ai.Document {
+	documents := make([]*ai.Document, n)
+	for i := range documents {
+		documents[i] = ai.DocumentFromText(fmt.Sprintf("doc %d", i), nil)
+	}
+	return documents
+}
+
+// decodeRequest decodes a JSON request body
+func decodeRequest(t *testing.T, r *http.Request) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Errorf("decode request: %v", err)
+	}
+	return body
+}
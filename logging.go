@@ -0,0 +1,78 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// logGeneration logs a completed model call at debug level when a Logger is configured
+func (a *AzureAIFoundry) logGeneration(ctx context.Context, modelName string, input *ai.ModelRequest, resp *ai.ModelResponse, err error, latency time.Duration) {
+	if a.Logger == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("model", modelName),
+		slog.Duration("latency", latency),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		a.Logger.LogAttrs(ctx, slog.LevelDebug, "azureaifoundry: generate failed", attrs...)
+		return
+	}
+
+	attrs = append(attrs, slog.String("finishReason", string(resp.FinishReason)))
+	if resp.Usage != nil {
+		attrs = append(attrs,
+			slog.Int("inputTokens", resp.Usage.InputTokens),
+			slog.Int("outputTokens", resp.Usage.OutputTokens),
+			slog.Int("cachedTokens", resp.Usage.CachedContentTokens),
+			slog.Int("reasoningTokens", resp.Usage.ThoughtsTokens),
+		)
+	}
+	if a.LogContent {
+		attrs = append(attrs, slog.Any("messages", input.Messages))
+		if resp.Message != nil {
+			attrs = append(attrs, slog.String("response", resp.Message.Text()))
+		}
+	}
+	a.Logger.LogAttrs(ctx, slog.LevelDebug, "azureaifoundry: generate", attrs...)
+}
+
+// logEmbedding logs a completed embedding call at debug level when a Logger is configured
+func (a *AzureAIFoundry) logEmbedding(ctx context.Context, modelName string, req *ai.EmbedRequest, err error, latency time.Duration) {
+	if a.Logger == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("model", modelName),
+		slog.Int("documents", len(req.Input)),
+		slog.Duration("latency", latency),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		a.Logger.LogAttrs(ctx, slog.LevelDebug, "azureaifoundry: embed failed", attrs...)
+		return
+	}
+	a.Logger.LogAttrs(ctx, slog.LevelDebug, "azureaifoundry: embed", attrs...)
+}
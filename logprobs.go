@@ -0,0 +1,89 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"github.com/firebase/genkit/go/ai"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/responses"
+)
+
+// maxTopLogprobs is the largest top_logprobs value accepted by Azure OpenAI
+const maxTopLogprobs = 20
+
+// TokenLogprob is the log probability of one generated token. It is attached to the
+// response message metadata under "logprobs" when the "logprobs" config key is set.
+type TokenLogprob struct {
+	Token       string       `json:"token"`                 // Generated token
+	Logprob     float64      `json:"logprob"`               // Log probability of the token
+	TopLogprobs []TopLogprob `json:"topLogprobs,omitempty"` // Most likely alternatives (config key "topLogprobs")
+}
+
+// TopLogprob is one of the most likely alternatives at a token position
+type TopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+}
+
+// convertLogprobs converts OpenAI token log probabilities to the plugin format
+func convertLogprobs(tokens []openai.ChatCompletionTokenLogprob) []TokenLogprob {
+	out := make([]TokenLogprob, 0, len(tokens))
+	for _, token := range tokens {
+		converted := TokenLogprob{
+			Token:   token.Token,
+			Logprob: token.Logprob,
+		}
+		for _, top := range token.TopLogprobs {
+			converted.TopLogprobs = append(converted.TopLogprobs, TopLogprob{
+				Token:   top.Token,
+				Logprob: top.Logprob,
+			})
+		}
+		out = append(out, converted)
+	}
+	return out
+}
+
+// convertResponsesLogprobs converts Responses API token log probabilities to the plugin format
+func convertResponsesLogprobs(tokens []responses.ResponseOutputTextLogprob) []TokenLogprob {
+	out := make([]TokenLogprob, 0, len(tokens))
+	for _, token := range tokens {
+		converted := TokenLogprob{
+			Token:   token.Token,
+			Logprob: token.Logprob,
+		}
+		for _, top := range token.TopLogprobs {
+			converted.TopLogprobs = append(converted.TopLogprobs, TopLogprob{
+				Token:   top.Token,
+				Logprob: top.Logprob,
+			})
+		}
+		out = append(out, converted)
+	}
+	return out
+}
+
+// setLogprobs records per-token log probabilities on a message
+func setLogprobs(message *ai.Message, logprobs []TokenLogprob) {
+	if len(logprobs) == 0 {
+		return
+	}
+	if message.Metadata == nil {
+		message.Metadata = make(map[string]any)
+	}
+	message.Metadata["logprobs"] = logprobs
+}
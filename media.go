@@ -0,0 +1,151 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// defaultMaxInlineImageBytes is the default size limit for images downloaded by the plugin
+const defaultMaxInlineImageBytes = 20 << 20
+
+// inlineImageURLs returns a copy of the messages where remote image URLs are downloaded
+// and replaced by base64 data URIs. The input messages are not modified.
+func (a *AzureAIFoundry) inlineImageURLs(ctx context.Context, messages []*ai.Message) ([]*ai.Message, error) {
+	out := make([]*ai.Message, len(messages))
+	for i, msg := range messages {
+		out[i] = msg
+		if msg.Role != ai.RoleUser {
+			continue
+		}
+
+		var content []*ai.Part
+		for j, part := range msg.Content {
+			if !part.IsMedia() || !isImagePart(part) || !isRemoteURL(part.Text) {
+				continue
+			}
+			if content == nil {
+				content = append([]*ai.Part(nil), msg.Content...)
+			}
+
+			dataURI, contentType, err := a.fetchImage(ctx, part.Text)
+			if err != nil {
+				return nil, err
+			}
+			content[j] = ai.NewMediaPart(contentType, dataURI)
+		}
+
+		if content != nil {
+			copied := *msg
+			copied.Content = content
+			out[i] = &copied
+		}
+	}
+	return out, nil
+}
+
+// fetchImage downloads an image and returns it as a data URI along with its content type
+func (a *AzureAIFoundry) fetchImage(ctx context.Context, url string) (string, string, error) {
+	maxBytes := a.MaxInlineImageBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxInlineImageBytes
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch image %s: %w", url, err)
+	}
+	client := a.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch image %s: %w", url, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to fetch image %s: status %d", url, resp.StatusCode)
+	}
+
+	contentType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(contentType, "image/") {
+		return "", "", fmt.Errorf("failed to fetch image %s: unsupported content type %q", url, resp.Header.Get("Content-Type"))
+	}
+
+	// Read one byte past the limit to detect oversized images
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read image %s: %w", url, err)
+	}
+	if int64(len(data)) > maxBytes {
+		return "", "", fmt.Errorf("image %s exceeds %d bytes", url, maxBytes)
+	}
+
+	return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data), contentType, nil
+}
+
+// isAudioPart reports whether a media part holds audio, by content type or data URI
+func isAudioPart(part *ai.Part) bool {
+	if part.ContentType != "" {
+		return strings.HasPrefix(part.ContentType, "audio/")
+	}
+	return strings.HasPrefix(part.Text, "data:audio/")
+}
+
+// inputAudioFromPart returns the base64 payload and format ("wav" or "mp3") of an audio part.
+// Chat completions only accept inline audio, given as a data URI or raw base64.
+func inputAudioFromPart(part *ai.Part) (string, string, error) {
+	contentType, data := part.ContentType, part.Text
+	if isRemoteURL(data) {
+		return "", "", fmt.Errorf("audio input must be inline data, not a URL: %s", data)
+	}
+	if rest, ok := strings.CutPrefix(data, "data:"); ok {
+		header, payload, found := strings.Cut(rest, ",")
+		if !found || !strings.HasSuffix(header, ";base64") {
+			return "", "", fmt.Errorf("audio input must be a base64 data URI")
+		}
+		if contentType == "" {
+			contentType = strings.TrimSuffix(header, ";base64")
+		}
+		data = payload
+	}
+
+	switch contentType {
+	case "audio/wav", "audio/wave", "audio/x-wav":
+		return data, "wav", nil
+	case "audio/mpeg", "audio/mp3":
+		return data, "mp3", nil
+	}
+	return "", "", fmt.Errorf("unsupported audio input format %q (wav or mp3 only)", contentType)
+}
+
+// isRemoteURL reports whether a media URL points to a remote http(s) resource
+func isRemoteURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
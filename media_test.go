@@ -0,0 +1,99 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestInlineImageURLs(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	inlined := "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+
+	tests := []struct {
+		name    string
+		inline  bool
+		path    string // Image path on the stub server
+		want    string // Image URL sent to the model; empty when relative to the server
+		wantErr string
+	}{
+		{name: "passed as is", inline: false, path: "/cat.png"},
+		{name: "downloaded and inlined", inline: true, path: "/cat.png", want: inlined},
+		{name: "non-image content type rejected", inline: true, path: "/page.html", wantErr: "unsupported content type"},
+		{name: "oversized image rejected", inline: true, path: "/large.png", wantErr: "exceeds 16 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent string
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/cat.png":
+					w.Header().Set("Content-Type", "image/png")
+					_, _ = w.Write(png)
+				case "/large.png":
+					w.Header().Set("Content-Type", "image/png")
+					_, _ = w.Write([]byte(strings.Repeat("x", 17)))
+				case "/page.html":
+					w.Header().Set("Content-Type", "text/html; charset=utf-8")
+					_, _ = w.Write([]byte("<html></html>"))
+				default:
+					body := decodeRequest(t, r)
+					content := body["messages"].([]any)[0].(map[string]any)["content"].([]any)
+					for _, part := range content {
+						if imageURL, ok := part.(map[string]any)["image_url"].(map[string]any); ok {
+							sent, _ = imageURL["url"].(string)
+						}
+					}
+					writeChatCompletion(w, "a cat")
+				}
+			})
+			a := newTestPlugin(t, server, func(a *AzureAIFoundry) {
+				a.InlineImageURLs = tt.inline
+				a.MaxInlineImageBytes = 16
+			})
+
+			url := server.URL + tt.path
+			_, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserMessage(ai.NewTextPart("describe"), ai.NewMediaPart("image/png", url))},
+			}, nil)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want
+			if want == "" {
+				want = url
+			}
+			if sent != want {
+				t.Errorf("image URL sent = %q, want %q", sent, want)
+			}
+		})
+	}
+}
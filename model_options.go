@@ -0,0 +1,77 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"maps"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// ModelOption customizes a model defined with DefineModel
+type ModelOption func(*modelOptions)
+
+// modelOptions holds the settings collected from ModelOptions
+type modelOptions struct {
+	name     string                 // Registered model name, if different from the deployment
+	defaults map[string]interface{} // Config applied under each request's config
+}
+
+// modelSettings holds the settings of a model defined with DefineModel that generation needs
+type modelSettings struct {
+	api       string // API surface: "chat" (the default when empty), "responses" or "completions"
+	maxTokens int64  // Output token ceiling from ModelDefinition.MaxTokens; 0 for none
+}
+
+// WithDefaults sets default generation parameters for a model. The config is a GenerationConfig
+// or a config map; keys set in a request's config take precedence over it.
+//
+//	deterministic := azurePlugin.DefineModel(g, def, nil,
+//		azureaifoundry.WithDefaults(azureaifoundry.GenerationConfig{Temperature: &zero}))
+func WithDefaults(config any) ModelOption {
+	return func(o *modelOptions) {
+		if defaults, ok := requestConfig(config); ok {
+			o.defaults = defaults
+		}
+	}
+}
+
+// WithName registers the model under name instead of its deployment name, so the same
+// deployment can be defined several times (e.g. with different defaults). Calls still go
+// to ModelDefinition.Name.
+func WithName(name string) ModelOption {
+	return func(o *modelOptions) {
+		o.name = name
+	}
+}
+
+// applyConfigDefaults returns a copy of the request whose config is the defaults overlaid
+// with the request's own config. The input request is not modified.
+func applyConfigDefaults(input *ai.ModelRequest, defaults map[string]interface{}) *ai.ModelRequest {
+	if len(defaults) == 0 {
+		return input
+	}
+
+	config := maps.Clone(defaults)
+	if requested, ok := requestConfig(input.Config); ok {
+		maps.Copy(config, requested)
+	}
+
+	merged := *input
+	merged.Config = config
+	return &merged
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// tokenBucket is a bucket refilled continuously up to its per-minute capacity
+type tokenBucket struct {
+	capacity  float64 // Maximum level, the per-minute limit
+	perSecond float64 // Refill rate
+	level     float64 // Available units; negative after a call used more than estimated
+	last      time.Time
+}
+
+// newTokenBucket returns a full bucket for a per-minute limit, or nil when the limit is not set
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity:  float64(perMinute),
+		perSecond: float64(perMinute) / 60,
+		level:     float64(perMinute),
+		last:      now,
+	}
+}
+
+// refill adds the units accrued since the last refill
+func (b *tokenBucket) refill(now time.Time) {
+	b.level = min(b.capacity, b.level+now.Sub(b.last).Seconds()*b.perSecond)
+	b.last = now
+}
+
+// wait returns how long until n units are available (0 if they are now)
+func (b *tokenBucket) wait(n float64) time.Duration {
+	if b.level >= n {
+		return 0
+	}
+	return time.Duration((n - b.level) / b.perSecond * float64(time.Second))
+}
+
+// rateLimiter throttles calls client-side to stay under Azure RPM and TPM quotas.
+// A nil limiter lets every call through.
+type rateLimiter struct {
+	mu       sync.Mutex
+	requests *tokenBucket // Requests per minute, or nil
+	tokens   *tokenBucket // Tokens per minute, or nil
+}
+
+// newRateLimiter returns a limiter for the given quotas, or nil when neither is set
+func newRateLimiter(requestsPerMinute, tokensPerMinute int) *rateLimiter {
+	if requestsPerMinute <= 0 && tokensPerMinute <= 0 {
+		return nil
+	}
+	now := time.Now()
+	return &rateLimiter{
+		requests: newTokenBucket(requestsPerMinute, now),
+		tokens:   newTokenBucket(tokensPerMinute, now),
+	}
+}
+
+// wait blocks until a call estimated at the given number of tokens fits both quotas, then
+// takes its share and returns the tokens taken, which reconcile must be given. Estimates
+// above the per-minute limit are capped so they can still run.
+func (l *rateLimiter) wait(ctx context.Context, tokens int) (int, error) {
+	if l == nil {
+		return 0, nil
+	}
+
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		var delay time.Duration
+		need := float64(tokens)
+		if l.requests != nil {
+			l.requests.refill(now)
+			delay = max(delay, l.requests.wait(1))
+		}
+		if l.tokens != nil {
+			l.tokens.refill(now)
+			need = min(need, l.tokens.capacity)
+			delay = max(delay, l.tokens.wait(need))
+		}
+		if delay == 0 {
+			if l.requests != nil {
+				l.requests.level--
+			}
+			taken := 0
+			if l.tokens != nil {
+				l.tokens.level -= need
+				taken = int(need)
+			}
+			l.mu.Unlock()
+			return taken, nil
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reconcile corrects the token quota once a call's actual usage is known, returning tokens
+// taken by wait beyond the usage and charging usage beyond them
+func (l *rateLimiter) reconcile(taken int, usage *ai.GenerationUsage) {
+	if l == nil || l.tokens == nil || usage == nil || usage.TotalTokens == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.refill(time.Now())
+	l.tokens.level = min(l.tokens.capacity, l.tokens.level+float64(taken-usage.TotalTokens))
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestRateLimiterReconcilesTokensTaken(t *testing.T) {
+	tests := []struct {
+		name      string
+		estimate  int
+		used      int
+		wantTaken int
+		wantLevel float64
+	}{
+		{name: "overestimate is returned", estimate: 30, used: 10, wantTaken: 30, wantLevel: 90},
+		{name: "underestimate is charged", estimate: 30, used: 50, wantTaken: 30, wantLevel: 50},
+		// Only the capped 100 tokens were taken, so a 500-token estimate must not refill the bucket
+		{name: "capped estimate", estimate: 500, used: 80, wantTaken: 100, wantLevel: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRateLimiter(0, 100)
+			l.tokens.perSecond = 0 // Freeze refills so levels are exact
+
+			taken, err := l.wait(context.Background(), tt.estimate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if taken != tt.wantTaken {
+				t.Errorf("taken = %d, want %d", taken, tt.wantTaken)
+			}
+
+			l.reconcile(taken, &ai.GenerationUsage{TotalTokens: tt.used})
+			if math.Abs(l.tokens.level-tt.wantLevel) > 1e-9 {
+				t.Errorf("level after reconcile = %v, want %v", l.tokens.level, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWaitHonorsContext(t *testing.T) {
+	l := newRateLimiter(1, 0)
+	if _, err := l.wait(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The single request per minute is used up; the next call would wait about a minute
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.wait(ctx, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+
+	var none *rateLimiter
+	if taken, err := none.wait(context.Background(), 50); taken != 0 || err != nil {
+		t.Errorf("nil limiter wait = %d, %v; want 0, nil", taken, err)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"github.com/firebase/genkit/go/ai"
+	"github.com/openai/openai-go/v3"
+)
+
+// RawResponseKey is the metadata key holding the API response when RawResponse is set:
+//
+//   - chat completions: *openai.ChatCompletion in the message metadata (not set when streaming)
+//   - Responses API: *responses.Response in the message metadata
+//   - legacy completions: *openai.Completion in the message metadata (not set when streaming)
+//   - embeddings: *openai.CreateEmbeddingResponse without its vectors, in each embedding's metadata
+const RawResponseKey = "rawResponse"
+
+// setRawResponse attaches the API response to every candidate message when RawResponse is set
+func (a *AzureAIFoundry) setRawResponse(resp *ai.ModelResponse, raw any) {
+	if !a.RawResponse || resp == nil {
+		return
+	}
+	for _, message := range responseMessages(resp) {
+		if message.Metadata == nil {
+			message.Metadata = make(map[string]any)
+		}
+		message.Metadata[RawResponseKey] = raw
+	}
+}
+
+// setRawEmbeddingResponse attaches an embeddings response, minus the vectors already
+// returned, to each embedding of its batch when RawResponse is set
+func (a *AzureAIFoundry) setRawEmbeddingResponse(embeddings []*ai.Embedding, resp *openai.CreateEmbeddingResponse) {
+	if !a.RawResponse {
+		return
+	}
+	raw := *resp
+	raw.Data = nil
+	for _, embedding := range embeddings {
+		if embedding.Metadata == nil {
+			embedding.Metadata = make(map[string]any)
+		}
+		embedding.Metadata[RawResponseKey] = &raw
+	}
+}
@@ -0,0 +1,525 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/firebase/genkit/go/ai"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"github.com/openai/openai-go/v3/responses"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+// API surfaces a chat model can be served through
+const (
+	APIChat      = "chat"      // Chat Completions API (default)
+	APIResponses = "responses" // Responses API
+)
+
+// StateStore maps logical conversation IDs to the ID of the latest Azure response in that
+// conversation, letting the Responses API continue a thread server-side.
+// Implementations must be safe for concurrent use.
+type StateStore interface {
+	// Get returns the latest response ID for a conversation, if any
+	Get(ctx context.Context, conversationID string) (responseID string, ok bool, err error)
+	// Set records the latest response ID for a conversation
+	Set(ctx context.Context, conversationID, responseID string) error
+}
+
+// InMemoryStateStore is a process-local StateStore
+type InMemoryStateStore struct {
+	mu        sync.Mutex
+	responses map[string]string
+}
+
+// NewInMemoryStateStore creates an empty in-memory StateStore
+func NewInMemoryStateStore() *InMemoryStateStore {
+	return &InMemoryStateStore{responses: make(map[string]string)}
+}
+
+// Get returns the latest response ID for a conversation, if any
+func (s *InMemoryStateStore) Get(_ context.Context, conversationID string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	responseID, ok := s.responses[conversationID]
+	return responseID, ok, nil
+}
+
+// Set records the latest response ID for a conversation
+func (s *InMemoryStateStore) Set(_ context.Context, conversationID, responseID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[conversationID] = responseID
+	return nil
+}
+
+// generateResponse handles text generation through the Responses API
+func (a *AzureAIFoundry) generateResponse(ctx context.Context, modelName string, input *ai.ModelRequest, cb func(context.Context, *ai.ModelResponseChunk) error) (*ai.ModelResponse, error) {
+	config, err := a.extractConfigFromRequest(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config for model '%s': %w", modelName, err)
+	}
+	if err := checkResponsesConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid config for model '%s': %w", modelName, err)
+	}
+
+	params := responses.ResponseNewParams{
+		Model: responses.ResponsesModel(modelName),
+	}
+	if config.maxTokens != nil {
+		params.MaxOutputTokens = openai.Int(*config.maxTokens)
+	}
+	if config.user != "" {
+		params.User = openai.String(config.user)
+	}
+	if supportsPromptCaching(modelName) {
+		if config.cacheKey != "" {
+			params.PromptCacheKey = openai.String(config.cacheKey)
+		}
+		if config.cacheRetention != "" {
+			params.PromptCacheRetention = responses.ResponseNewParamsPromptCacheRetention(config.cacheRetention)
+		}
+	}
+	if config.serviceTier != "" {
+		params.ServiceTier = responses.ResponseNewParamsServiceTier(config.serviceTier)
+	}
+	if config.store != nil {
+		params.Store = openai.Bool(*config.store)
+	}
+	if len(config.metadata) > 0 {
+		params.Metadata = config.metadata
+	}
+	if isReasoningModel(modelName) {
+		// Reasoning models reject sampling parameters
+		if config.reasoningEffort != "" {
+			params.Reasoning = shared.ReasoningParam{
+				Effort: shared.ReasoningEffort(config.reasoningEffort),
+			}
+		}
+	} else {
+		if config.temperature != nil {
+			params.Temperature = openai.Float(*config.temperature)
+		}
+		if config.topP != nil {
+			params.TopP = openai.Float(*config.topP)
+		}
+	}
+
+	if config.logprobs {
+		params.Include = append(params.Include, responses.ResponseIncludableMessageOutputTextLogprobs)
+		if config.topLogprobs != nil {
+			params.TopLogprobs = openai.Int(*config.topLogprobs)
+		}
+	}
+
+	// Request JSON output when the caller asked for structured output
+	if input.Output != nil && input.Output.Format == ai.OutputFormatJSON {
+		if input.Output.Schema != nil {
+			params.Text.Format.OfJSONSchema = &responses.ResponseFormatTextJSONSchemaConfigParam{
+				Name:   "output",
+				Schema: input.Output.Schema,
+			}
+		} else {
+			params.Text.Format.OfJSONObject = &shared.ResponseFormatJSONObjectParam{}
+		}
+	}
+
+	// Continue a stored conversation: the server already holds earlier turns
+	messages := input.Messages
+	conversationID := conversationIDFromConfig(input)
+	if conversationID != "" && a.StateStore != nil {
+		previousID, ok, err := a.StateStore.Get(ctx, conversationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load conversation state for '%s': %w", conversationID, err)
+		}
+		if ok {
+			params.PreviousResponseID = openai.String(previousID)
+			messages = messagesAfterLastModelTurn(messages)
+		}
+		params.Store = openai.Bool(true)
+	}
+	inputItems, err := a.convertMessagesToResponsesInput(mergeSystemMessages(messages, a.MergeSystemMessages))
+	if err != nil {
+		return nil, fmt.Errorf("invalid messages for model '%s': %w", modelName, err)
+	}
+	params.Input = responses.ResponseNewParamsInputUnion{
+		OfInputItemList: inputItems,
+	}
+
+	// Handle tools
+	for _, tool := range input.Tools {
+		schema, strict := tool.InputSchema, false
+		if schema != nil && a.AutoToolSchemaRepair {
+			schema, strict = repairToolSchema(schema)
+		}
+		toolParam := responses.ToolParamOfFunction(tool.Name, schema, strict)
+		if tool.Description != "" {
+			toolParam.OfFunction.Description = openai.String(tool.Description)
+		}
+		params.Tools = append(params.Tools, toolParam)
+	}
+	if len(params.Tools) > 0 {
+		if config.parallelTools != nil {
+			params.ParallelToolCalls = openai.Bool(*config.parallelTools)
+		}
+		switch config.toolChoice {
+		case "auto":
+			params.ToolChoice.OfToolChoiceMode = openai.Opt(responses.ToolChoiceOptionsAuto)
+		case "required":
+			params.ToolChoice.OfToolChoiceMode = openai.Opt(responses.ToolChoiceOptionsRequired)
+		case "none":
+			params.ToolChoice.OfToolChoiceMode = openai.Opt(responses.ToolChoiceOptionsNone)
+		case "function":
+			if err := validateToolFunction(config.toolFunction, input.Tools); err != nil {
+				return nil, fmt.Errorf("invalid config for model '%s': %w", modelName, err)
+			}
+			params.ToolChoice.OfFunctionTool = &responses.ToolChoiceFunctionParam{
+				Name: config.toolFunction,
+			}
+		}
+	}
+
+	var resp *responses.Response
+	var httpResp *http.Response
+	if cb != nil {
+		var partial *ai.ModelResponse
+		resp, partial, err = a.generateResponseStream(ctx, params, cb, option.WithResponseInto(&httpResp))
+		if partial != nil {
+			// Stopped with WithStreamStop: there is no final response to store or convert
+			setServedBy(partial, httpResp)
+			return partial, nil
+		}
+	} else {
+		err = a.withRetry(ctx, func() error {
+			var err error
+			resp, err = a.client.Responses.New(ctx, params, option.WithResponseInto(&httpResp))
+			return err
+		})
+		if err != nil {
+			err = fmt.Errorf("response generation failed for model '%s': %w", modelName, classifyError(err))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A failed response is not a conversation turn: don't store or convert it
+	if resp.Status == responses.ResponseStatusFailed {
+		return nil, fmt.Errorf("response failed: %s", resp.Error.Message)
+	}
+
+	if conversationID != "" && a.StateStore != nil {
+		if err := a.StateStore.Set(ctx, conversationID, resp.ID); err != nil {
+			return nil, fmt.Errorf("failed to save conversation state for '%s': %w", conversationID, err)
+		}
+	}
+
+	result, err := a.convertResponsesResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	setServiceTier(result, string(resp.ServiceTier))
+	setServedBy(result, httpResp)
+	a.setRawResponse(result, resp)
+	return result, nil
+}
+
+// checkResponsesConfig rejects config keys the Responses API has no parameter for, rather
+// than dropping them silently
+func checkResponsesConfig(config *modelConfig) error {
+	var unsupported []string
+	if len(config.stopSequences) > 0 {
+		unsupported = append(unsupported, "stopSequences")
+	}
+	if config.seed != nil {
+		unsupported = append(unsupported, "seed")
+	}
+	if config.n != nil && *config.n > 1 {
+		unsupported = append(unsupported, "n")
+	}
+	if config.frequencyPenalty != nil {
+		unsupported = append(unsupported, "frequencyPenalty")
+	}
+	if config.presencePenalty != nil {
+		unsupported = append(unsupported, "presencePenalty")
+	}
+	if len(config.logitBias) > 0 {
+		unsupported = append(unsupported, "logitBias")
+	}
+	if config.audioVoice != "" {
+		unsupported = append(unsupported, "audio")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("the Responses API does not support %s", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// generateResponseStream streams a Responses API call, forwarding text and reasoning deltas.
+// When stopped with WithStreamStop it returns, instead of the final response, a partial one
+// holding what was streamed so far.
+func (a *AzureAIFoundry) generateResponseStream(ctx context.Context, params responses.ResponseNewParams, cb func(context.Context, *ai.ModelResponseChunk) error, opts ...option.RequestOption) (*responses.Response, *ai.ModelResponse, error) {
+	// A stop from WithStreamStop cancels only this request, leaving ctx intact
+	reqCtx, cancelReq := context.WithCancel(ctx)
+	defer cancelReq()
+	if signal := streamStopSignal(ctx); signal != nil {
+		defer context.AfterFunc(signal, cancelReq)()
+	}
+
+	stream := a.client.Responses.NewStreaming(reqCtx, params, opts...)
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	var final *responses.Response
+	var text, reasoning strings.Builder
+	for stream.Next() {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		if streamStopped(ctx) {
+			break
+		}
+
+		event := stream.Current()
+
+		var part *ai.Part
+		switch event.Type {
+		case "response.output_text.delta":
+			text.WriteString(event.Delta)
+			part = ai.NewTextPart(event.Delta)
+		case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+			reasoning.WriteString(event.Delta)
+			part = newReasoningPart(event.Delta)
+		case "response.completed", "response.incomplete", "response.failed":
+			resp := event.Response
+			final = &resp
+		}
+
+		if part != nil && part.Text != "" {
+			if err := cb(ctx, &ai.ModelResponseChunk{Content: []*ai.Part{part}}); err != nil {
+				return nil, nil, fmt.Errorf("streaming callback error: %w", err)
+			}
+		}
+	}
+
+	if streamStopped(ctx) {
+		content := []*ai.Part{}
+		if reasoning.Len() > 0 {
+			content = append(content, newReasoningPart(reasoning.String()))
+		}
+		if text.Len() > 0 {
+			content = append(content, ai.NewTextPart(text.String()))
+		}
+		return nil, &ai.ModelResponse{
+			Message:       &ai.Message{Role: ai.RoleModel, Content: content},
+			FinishReason:  ai.FinishReasonInterrupted,
+			FinishMessage: "stopped",
+			Usage:         &ai.GenerationUsage{},
+		}, nil
+	}
+	if err := stream.Err(); err != nil {
+		return nil, nil, fmt.Errorf("stream error: %w", classifyError(err))
+	}
+	if final == nil {
+		return nil, nil, fmt.Errorf("stream ended without a final response")
+	}
+	return final, nil, nil
+}
+
+// convertMessagesToResponsesInput converts Genkit messages to Responses API input items.
+// Roles are mapped as in convertMessagesToOpenAI; model text and tool calls keep their order.
+func (a *AzureAIFoundry) convertMessagesToResponsesInput(messages []*ai.Message) (responses.ResponseInputParam, error) {
+	var items responses.ResponseInputParam
+
+	for i, msg := range messages {
+		if len(msg.Content) == 0 {
+			continue // Skip messages with no content
+		}
+		role, err := messageRole(msg.Role)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+
+		switch role {
+		case ai.RoleSystem:
+			items = append(items, responses.ResponseInputItemParamOfMessage(a.messageText(msg), responses.EasyInputMessageRoleSystem))
+		case ai.RoleUser:
+			var content responses.ResponseInputMessageContentListParam
+			for _, part := range msg.Content {
+				if part.IsText() {
+					content = append(content, responses.ResponseInputContentUnionParam{
+						OfInputText: &responses.ResponseInputTextParam{Text: a.prepareText(part.Text)},
+					})
+				} else if part.IsMedia() && isImagePart(part) {
+					content = append(content, responses.ResponseInputContentUnionParam{
+						OfInputImage: &responses.ResponseInputImageParam{
+							ImageURL: openai.String(imageURLFromPart(part)),
+							Detail:   responses.ResponseInputImageDetailAuto,
+						},
+					})
+				} else if part.IsMedia() {
+					// Message content takes text, images and files only; audio goes through chat completions
+					kind := part.ContentType
+					if kind == "" && isAudioPart(part) {
+						kind = "audio"
+					}
+					return nil, fmt.Errorf("message %d: the Responses API does not accept %s input; send it with the chat API (\"api\": \"chat\")", i, cmp.Or(kind, "non-image media"))
+				}
+			}
+			items = append(items, responses.ResponseInputItemParamOfMessage(content, responses.EasyInputMessageRoleUser))
+		case ai.RoleModel:
+			// Input items can interleave, so text and tool calls keep their original order;
+			// adjacent text parts form one assistant message
+			var text string
+			flush := func() {
+				if text != "" {
+					items = append(items, responses.ResponseInputItemParamOfMessage(text, responses.EasyInputMessageRoleAssistant))
+					text = ""
+				}
+			}
+			for _, part := range msg.Content {
+				if part.IsText() {
+					text += a.prepareText(part.Text)
+					continue
+				}
+				if !part.IsToolRequest() {
+					continue
+				}
+				argsJSON, err := json.Marshal(part.ToolRequest.Input)
+				if err != nil {
+					continue
+				}
+				flush()
+				items = append(items, responses.ResponseInputItemParamOfFunctionCall(string(argsJSON), toolCallID(part.ToolRequest.Ref, part.ToolRequest.Name), part.ToolRequest.Name))
+			}
+			flush()
+		case ai.RoleTool:
+			for _, part := range msg.Content {
+				if !part.IsToolResponse() {
+					continue
+				}
+				output, err := toolOutputText(part.ToolResponse.Output)
+				if err != nil {
+					continue
+				}
+				items = append(items, responses.ResponseInputItemParamOfFunctionCallOutput(toolCallID(part.ToolResponse.Ref, part.ToolResponse.Name), output))
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// convertResponsesResponse converts a Responses API response to Genkit format
+func (a *AzureAIFoundry) convertResponsesResponse(resp *responses.Response) (*ai.ModelResponse, error) {
+	var content []*ai.Part
+	var logprobs []TokenLogprob
+	for _, item := range resp.Output {
+		switch item.Type {
+		case "reasoning":
+			for _, summary := range item.Summary {
+				if summary.Text != "" {
+					content = append(content, newReasoningPart(summary.Text))
+				}
+			}
+		case "message":
+			for _, c := range item.Content {
+				if c.Type == "output_text" && c.Text != "" {
+					content = append(content, ai.NewTextPart(c.Text))
+					logprobs = append(logprobs, convertResponsesLogprobs(c.Logprobs)...)
+				}
+			}
+		case "function_call":
+			args, err := parseToolArguments(item.Arguments)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal tool arguments for '%s': %w", item.Name, err)
+			}
+			content = append(content, ai.NewToolRequestPart(&ai.ToolRequest{
+				Name:  item.Name,
+				Ref:   item.CallID,
+				Input: args,
+			}))
+		}
+	}
+
+	if a.EmptyResponseIsError && len(content) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	finishReason := ai.FinishReasonStop
+	if resp.Status == responses.ResponseStatusIncomplete {
+		switch resp.IncompleteDetails.Reason {
+		case "max_output_tokens":
+			finishReason = ai.FinishReasonLength
+		case "content_filter":
+			finishReason = ai.FinishReasonBlocked
+		default:
+			finishReason = ai.FinishReasonOther
+		}
+	}
+
+	message := &ai.Message{
+		Role:     ai.RoleModel,
+		Content:  content,
+		Metadata: map[string]any{"responseId": resp.ID},
+	}
+	a.applyToolCallBudget(message)
+	setLogprobs(message, logprobs)
+
+	usage := &ai.GenerationUsage{
+		InputTokens:         int(resp.Usage.InputTokens),
+		OutputTokens:        int(resp.Usage.OutputTokens),
+		TotalTokens:         int(resp.Usage.TotalTokens),
+		CachedContentTokens: int(resp.Usage.InputTokensDetails.CachedTokens),
+		ThoughtsTokens:      int(resp.Usage.OutputTokensDetails.ReasoningTokens),
+	}
+
+	return &ai.ModelResponse{
+		Message:      message,
+		FinishReason: finishReason,
+		Usage:        usage,
+	}, nil
+}
+
+// conversationIDFromConfig reads the logical conversation ID from the request config
+func conversationIDFromConfig(input *ai.ModelRequest) string {
+	configMap, ok := requestConfig(input.Config)
+	if !ok {
+		return ""
+	}
+	conversationID, _ := configMap["conversationId"].(string)
+	return conversationID
+}
+
+// messagesAfterLastModelTurn returns the messages following the last model message,
+// which are the only ones the server has not seen in a stored conversation
+func messagesAfterLastModelTurn(messages []*ai.Message) []*ai.Message {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == ai.RoleModel {
+			return messages[i+1:]
+		}
+	}
+	return messages
+}
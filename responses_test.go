@@ -0,0 +1,352 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestResponsesStreamTagsReasoning(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/responses" {
+			t.Errorf("path = %q, want /responses", r.URL.Path)
+		}
+		reasoning := map[string]any{
+			"type":    "reasoning",
+			"id":      "rs_test",
+			"summary": []any{map[string]any{"type": "summary_text", "text": "Check units. Convert."}},
+		}
+		writeEvents(w,
+			map[string]any{"type": "response.reasoning_summary_text.delta", "item_id": "rs_test", "output_index": 0, "summary_index": 0, "delta": "Check units. "},
+			map[string]any{"type": "response.output_text.delta", "item_id": "msg_test", "output_index": 1, "content_index": 0, "delta": "It is "},
+			map[string]any{"type": "response.reasoning_summary_text.delta", "item_id": "rs_test", "output_index": 0, "summary_index": 0, "delta": "Convert."},
+			map[string]any{"type": "response.output_text.delta", "item_id": "msg_test", "output_index": 1, "content_index": 0, "delta": "20 C."},
+			map[string]any{"type": "response.completed", "response": responsesResponse(reasoning, outputMessage("It is 20 C."))},
+		)
+	})
+	a := newTestPlugin(t, server, nil)
+
+	type chunk struct {
+		text      string
+		reasoning bool
+	}
+	var chunks []chunk
+	resp, err := a.generateText(context.Background(), "o4-mini", modelSettings{}, &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("68 F in C?")},
+		Config:   map[string]interface{}{"api": "responses"},
+	}, func(_ context.Context, c *ai.ModelResponseChunk) error {
+		for _, part := range c.Content {
+			tagged, _ := part.Metadata["reasoning"].(bool)
+			if part.IsReasoning() != tagged {
+				t.Errorf("part %q: IsReasoning %v but reasoning metadata %v", part.Text, part.IsReasoning(), tagged)
+			}
+			chunks = append(chunks, chunk{part.Text, tagged})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []chunk{{"Check units. ", true}, {"It is ", false}, {"Convert.", true}, {"20 C.", false}}
+	if len(chunks) != len(want) {
+		t.Fatalf("chunks = %v, want %v", chunks, want)
+	}
+	for i := range want {
+		if chunks[i] != want[i] {
+			t.Errorf("chunk %d = %+v, want %+v", i, chunks[i], want[i])
+		}
+	}
+
+	if resp.Text() != "It is 20 C." {
+		t.Errorf("response text = %q", resp.Text())
+	}
+	if resp.Reasoning() != "Check units. Convert." {
+		t.Errorf("response reasoning = %q", resp.Reasoning())
+	}
+}
+
+func TestStateStoreContinuesConversation(t *testing.T) {
+	var bodies []map[string]any
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		bodies = append(bodies, decodeRequest(t, r))
+		resp := responsesResponse(outputMessage("ok"))
+		resp["id"] = fmt.Sprintf("resp_%d", len(bodies))
+		writeJSON(w, http.StatusOK, resp)
+	})
+	store := NewInMemoryStateStore()
+	a := newTestPlugin(t, server, func(a *AzureAIFoundry) { a.StateStore = store })
+
+	generate := func(conversationID string, messages ...*ai.Message) {
+		t.Helper()
+		_, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+			Messages: messages,
+			Config:   map[string]interface{}{"api": "responses", "conversationId": conversationID},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := ai.NewUserTextMessage("My name is Ada.")
+	generate("chat-1", first)
+	generate("chat-1", first, ai.NewModelTextMessage("ok"), ai.NewUserTextMessage("What is my name?"))
+	generate("chat-2", ai.NewUserTextMessage("Hello"))
+
+	if len(bodies) != 3 {
+		t.Fatalf("got %d requests, want 3", len(bodies))
+	}
+	for i, body := range bodies {
+		if body["store"] != true {
+			t.Errorf("request %d: store = %v, want true", i+1, body["store"])
+		}
+	}
+	if previous, ok := bodies[0]["previous_response_id"]; ok {
+		t.Errorf("first turn continued response %v", previous)
+	}
+	if previous := bodies[1]["previous_response_id"]; previous != "resp_1" {
+		t.Errorf("second turn previous_response_id = %v, want resp_1", previous)
+	}
+	if input := bodies[1]["input"].([]any); len(input) != 1 {
+		t.Errorf("second turn resent %d input items, want only the new user message", len(input))
+	}
+	if previous, ok := bodies[2]["previous_response_id"]; ok {
+		t.Errorf("another conversation continued response %v", previous)
+	}
+
+	if id, ok, _ := store.Get(context.Background(), "chat-1"); !ok || id != "resp_2" {
+		t.Errorf("stored response for chat-1 = %q, want resp_2", id)
+	}
+}
+
+func TestResponsesFailedStatusIsError(t *testing.T) {
+	failed := responsesResponse()
+	failed["status"] = "failed"
+	failed["error"] = map[string]any{"code": "server_error", "message": "the model crashed"}
+
+	for _, streaming := range []bool{false, true} {
+		t.Run(fmt.Sprintf("streaming=%v", streaming), func(t *testing.T) {
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if streaming {
+					writeEvents(w, map[string]any{"type": "response.failed", "response": failed})
+					return
+				}
+				writeJSON(w, http.StatusOK, failed)
+			})
+			store := NewInMemoryStateStore()
+			a := newTestPlugin(t, server, func(a *AzureAIFoundry) { a.StateStore = store })
+
+			var cb func(context.Context, *ai.ModelResponseChunk) error
+			if streaming {
+				cb = func(context.Context, *ai.ModelResponseChunk) error { return nil }
+			}
+			_, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+				Config:   map[string]interface{}{"api": "responses", "conversationId": "chat-1"},
+			}, cb)
+			if err == nil || !strings.Contains(err.Error(), "the model crashed") {
+				t.Errorf("error = %v, want the response's failure", err)
+			}
+			if id, ok, _ := store.Get(context.Background(), "chat-1"); ok {
+				t.Errorf("failed response %q stored as the conversation's latest", id)
+			}
+		})
+	}
+}
+
+func TestRequestAPIOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    string // "chat-model" is served by chat completions, "responses-model" by the Responses API
+		api      any    // Value of the "api" config key; nil leaves it unset
+		wantPath string
+		wantErr  string
+	}{
+		{name: "chat model default", model: "chat-model", wantPath: "/chat/completions"},
+		{name: "responses model default", model: "responses-model", wantPath: "/responses"},
+		{name: "chat model routed to responses", model: "chat-model", api: "responses", wantPath: "/responses"},
+		{name: "responses model routed to chat", model: "responses-model", api: "chat", wantPath: "/chat/completions"},
+		{name: "unknown api", model: "chat-model", api: "assistants", wantErr: `api must be "chat" or "responses"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				if r.URL.Path == "/responses" {
+					writeJSON(w, http.StatusOK, responsesResponse(outputMessage("ok")))
+					return
+				}
+				writeChatCompletion(w, "ok")
+			})
+			g, a := newTestGenkit(t, server, nil)
+			a.DefineModel(g, ModelDefinition{Name: "chat-model", Type: "chat"}, nil)
+			a.DefineModel(g, ModelDefinition{Name: "responses-model", Type: "chat", API: APIResponses}, nil)
+
+			config := map[string]interface{}{}
+			if tt.api != nil {
+				config["api"] = tt.api
+			}
+			resp, err := a.lookupModel(tt.model)(context.Background(), &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+				Config:   config,
+			}, nil)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				if len(paths) != 0 {
+					t.Errorf("requests sent to %v, want none", paths)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != 1 || paths[0] != tt.wantPath {
+				t.Errorf("requests sent to %v, want %s", paths, tt.wantPath)
+			}
+			if resp.Text() != "ok" {
+				t.Errorf("text = %q, want ok", resp.Text())
+			}
+		})
+	}
+}
+
+func TestAliasesKeepTheirOwnAPI(t *testing.T) {
+	var paths []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/responses" {
+			writeJSON(w, http.StatusOK, responsesResponse(outputMessage("ok")))
+			return
+		}
+		writeChatCompletion(w, "ok")
+	})
+	g, a := newTestGenkit(t, server, nil)
+	// Two aliases of one deployment, defined in an order where a shared setting would leak
+	a.DefineModel(g, ModelDefinition{Name: "gpt-4o", Type: "chat", API: APIResponses}, nil, WithName("stateful"))
+	a.DefineModel(g, ModelDefinition{Name: "gpt-4o", Type: "chat"}, nil, WithName("plain"))
+
+	for _, name := range []string{"stateful", "plain"} {
+		if _, err := a.lookupModel(name)(context.Background(), &ai.ModelRequest{
+			Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+		}, nil); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	if want := []string{"/responses", "/chat/completions"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
+
+func TestResponsesRejectsAudioInput(t *testing.T) {
+	var paths []string
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		writeChatCompletion(w, "A greeting.")
+	})
+	a := newTestPlugin(t, server, nil)
+	audio := ai.NewMediaPart("audio/wav", "data:audio/wav;base64,UklGRg==")
+
+	for _, api := range []string{APIChat, APIResponses} {
+		_, err := a.generateText(context.Background(), "gpt-4o-audio-preview", modelSettings{}, &ai.ModelRequest{
+			Messages: []*ai.Message{ai.NewUserMessage(ai.NewTextPart("What is said?"), audio)},
+			Config:   map[string]interface{}{"api": api},
+		}, nil)
+		switch {
+		case api == APIChat && err != nil:
+			t.Errorf("chat: %v", err)
+		case api == APIResponses && (err == nil || !strings.Contains(err.Error(), "audio/wav")):
+			t.Errorf("responses: error = %v, want audio rejected rather than dropped", err)
+		}
+	}
+	// The Responses API request is never sent
+	if want := []string{"/chat/completions"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
+
+func TestResponsesConfigMapping(t *testing.T) {
+	var body map[string]any
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body = decodeRequest(t, r)
+		message := outputMessage(`{"city":"Madrid"}`)
+		message["content"].([]any)[0].(map[string]any)["logprobs"] = []any{map[string]any{
+			"token": "{", "bytes": []int{123}, "logprob": -0.01,
+			"top_logprobs": []any{map[string]any{"token": "{", "bytes": []int{123}, "logprob": -0.01}},
+		}}
+		writeJSON(w, http.StatusOK, responsesResponse(message))
+	})
+	a := newTestPlugin(t, server, nil)
+
+	schema := map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}}
+	resp, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Capital of Spain?")},
+		Config:   map[string]interface{}{"api": "responses", "logprobs": true, "topLogprobs": 1},
+		Output:   &ai.ModelOutputConfig{Format: ai.OutputFormatJSON, Schema: schema},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format, _ := body["text"].(map[string]any)["format"].(map[string]any)
+	if format["type"] != "json_schema" || format["name"] != "output" || format["schema"] == nil {
+		t.Errorf("text.format = %v, want the output JSON schema", format)
+	}
+	if include, _ := body["include"].([]any); len(include) != 1 || include[0] != "message.output_text.logprobs" {
+		t.Errorf("include = %v, want output text logprobs", body["include"])
+	}
+	if body["top_logprobs"] != float64(1) {
+		t.Errorf("top_logprobs = %v, want 1", body["top_logprobs"])
+	}
+	logprobs, _ := resp.Message.Metadata["logprobs"].([]TokenLogprob)
+	if len(logprobs) != 1 || logprobs[0].Token != "{" || len(logprobs[0].TopLogprobs) != 1 {
+		t.Errorf("logprobs metadata = %+v", resp.Message.Metadata["logprobs"])
+	}
+
+	for key, value := range map[string]any{
+		"stopSequences":    []string{"END"},
+		"seed":             7,
+		"n":                2,
+		"frequencyPenalty": 0.5,
+		"presencePenalty":  0.5,
+		"logitBias":        map[string]any{"50256": -100},
+		"audio":            map[string]any{"voice": "alloy"},
+	} {
+		body = nil
+		_, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+			Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+			Config:   map[string]interface{}{"api": "responses", key: value},
+		}, nil)
+		if err == nil || !strings.Contains(err.Error(), "does not support "+key) {
+			t.Errorf("%s: error = %v, want it rejected", key, err)
+		}
+		if body != nil {
+			t.Errorf("%s: request sent", key)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// defaultRetryBaseDelay is the initial backoff delay when RetryBaseDelay is not set
+const defaultRetryBaseDelay = 500 * time.Millisecond
+
+// maxRetryDelay caps a single backoff delay
+const maxRetryDelay = 30 * time.Second
+
+// RetryError is returned when a call still fails after being retried. It records what
+// an Azure support case needs and unwraps to the last attempt's error.
+type RetryError struct {
+	Attempts   int           // Calls made, including the first
+	Elapsed    time.Duration // Time from the first call to giving up
+	StatusCode int           // HTTP status of the last attempt, 0 when it got no response
+	RequestID  string        // apim-request-id of the last response
+	// ClientRequestID is the x-ms-client-request-id of the last attempt, from the
+	// response or else the request
+	ClientRequestID string
+	Err             error // Last attempt's error
+}
+
+// Error describes the last failure along with the retry context
+func (e *RetryError) Error() string {
+	msg := fmt.Sprintf("giving up after %d attempts in %s", e.Attempts, e.Elapsed.Round(time.Millisecond))
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(", last status %d", e.StatusCode)
+	}
+	if e.RequestID != "" {
+		msg += ", apim-request-id " + e.RequestID
+	}
+	if e.ClientRequestID != "" {
+		msg += ", x-ms-client-request-id " + e.ClientRequestID
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the last attempt's error
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// retryFailure wraps the last error of a call in a *RetryError once it has been retried
+func retryFailure(err error, attempts int, start time.Time) error {
+	if attempts <= 1 {
+		return err
+	}
+	retryErr := &RetryError{
+		Attempts: attempts,
+		Elapsed:  time.Since(start),
+		Err:      err,
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		retryErr.StatusCode = apiErr.StatusCode
+		if apiErr.Response != nil {
+			retryErr.RequestID = apiErr.Response.Header.Get("apim-request-id")
+			retryErr.ClientRequestID = apiErr.Response.Header.Get("x-ms-client-request-id")
+		}
+		if retryErr.ClientRequestID == "" && apiErr.Request != nil {
+			retryErr.ClientRequestID = apiErr.Request.Header.Get("x-ms-client-request-id")
+		}
+	}
+	return retryErr
+}
+
+// withRetry calls fn, retrying on HTTP 429 and 500/502/503/504 with exponential backoff.
+// The Retry-After header is honored when present and context cancellation stops waiting.
+// A call that still fails after retrying returns a *RetryError.
+func (a *AzureAIFoundry) withRetry(ctx context.Context, fn func() error) error {
+	baseDelay := a.RetryBaseDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if attempt >= a.MaxRetries || !isRetryable(err) {
+			return retryFailure(err, attempt+1, start)
+		}
+
+		delay, ok := retryAfter(err)
+		if !ok {
+			delay = min(baseDelay<<attempt, maxRetryDelay)
+			if a.RetryJitter > 0 {
+				delay += rand.N(a.RetryJitter)
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retryFailure(err, attempt+1, start)
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryable reports whether an error is a transient Azure failure worth retrying
+func isRetryable(err error) bool {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter extracts the server-requested delay from a failed response, if any
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return 0, false
+	}
+	header := apiErr.Response.Header
+
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms >= 0 {
+		return min(time.Duration(ms*float64(time.Millisecond)), maxRetryDelay), true
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds*float64(time.Second)), maxRetryDelay), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return min(max(time.Until(date), 0), maxRetryDelay), true
+	}
+	return 0, false
+}
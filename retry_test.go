@@ -0,0 +1,93 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	var attempts atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.Header().Set("Retry-After-Ms", "150")
+			writeAPIError(w, http.StatusTooManyRequests, "429", "rate limited")
+			return
+		}
+		writeChatCompletion(w, "ok")
+	})
+	a := newTestPlugin(t, server, func(a *AzureAIFoundry) {
+		a.MaxRetries = 3
+		a.RetryBaseDelay = time.Millisecond
+	})
+
+	start := time.Now()
+	resp, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Text() != "ok" {
+		t.Errorf("text = %q, want ok", resp.Text())
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("attempts = %d, want 3", n)
+	}
+	// Two waits of the server-requested 150ms instead of the 1ms base delay
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("retried after %s, want Retry-After honored", elapsed)
+	}
+}
+
+func TestRetryErrorRecordsAttempts(t *testing.T) {
+	var attempts atomic.Int32
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "0")
+		w.Header().Set("apim-request-id", "req-123")
+		writeAPIError(w, http.StatusServiceUnavailable, "503", "unavailable")
+	})
+	a := newTestPlugin(t, server, func(a *AzureAIFoundry) {
+		a.MaxRetries = 2
+	})
+
+	_, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	}, nil)
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("error = %v, want a *RetryError", err)
+	}
+	if retryErr.Attempts != 3 || attempts.Load() != 3 {
+		t.Errorf("Attempts = %d (server saw %d), want 3", retryErr.Attempts, attempts.Load())
+	}
+	if retryErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("StatusCode = %d, want 503", retryErr.StatusCode)
+	}
+	if retryErr.RequestID != "req-123" {
+		t.Errorf("RequestID = %q, want req-123", retryErr.RequestID)
+	}
+}
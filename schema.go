@@ -0,0 +1,303 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// unsupportedSchemaKeywords lists JSON Schema keywords rejected by Azure OpenAI strict mode
+var unsupportedSchemaKeywords = []string{
+	"$schema",
+	"$id",
+	"default",
+	"examples",
+	"format",
+	"pattern",
+	"minLength",
+	"maxLength",
+	"minimum",
+	"maximum",
+	"exclusiveMinimum",
+	"exclusiveMaximum",
+	"multipleOf",
+	"minItems",
+	"maxItems",
+	"uniqueItems",
+	"minProperties",
+	"maxProperties",
+	"patternProperties",
+	"propertyNames",
+	"unevaluatedProperties",
+	"unevaluatedItems",
+	"contains",
+	"minContains",
+	"maxContains",
+}
+
+// repairToolSchema returns a copy of a tool input schema normalized for Azure strict mode,
+// and whether the tool can be sent with strict set. Object schemas whose additionalProperties
+// is missing or true get false. When every object is closed that way, all properties are
+// made required, the ones that were optional also accepting null so the model can still
+// leave them out, and keywords strict mode rejects are stripped. A schema-valued
+// additionalProperties (a map input) rules strict mode out, so the schema then keeps its
+// validation keywords and required list. The original schema is never modified.
+func repairToolSchema(schema map[string]any) (map[string]any, bool) {
+	if schema == nil {
+		return nil, false
+	}
+	strict := strictCompatible(schema)
+	return repairSchema(schema, strict), strict
+}
+
+// repairSchema repairs one schema and its nested schemas, applying the strict-only changes
+// when strict is set
+func repairSchema(schema map[string]any, strict bool) map[string]any {
+	repaired := make(map[string]any, len(schema))
+	for key, value := range schema {
+		repaired[key] = value
+	}
+
+	if strict {
+		for _, keyword := range unsupportedSchemaKeywords {
+			delete(repaired, keyword)
+		}
+	}
+
+	// Properties made required below that were optional
+	optional := map[string]bool{}
+	if isObjectSchema(repaired) {
+		if additional, present := repaired["additionalProperties"]; !present || additional == true {
+			repaired["additionalProperties"] = false
+		}
+		if props, ok := repaired["properties"].(map[string]any); ok && strict {
+			for name := range props {
+				optional[name] = !slices.Contains(requiredNames(schema["required"]), name)
+			}
+			repaired["required"] = slices.Sorted(maps.Keys(props))
+		}
+	}
+
+	// Recurse into nested schemas
+	if props, ok := repaired["properties"].(map[string]any); ok {
+		repairedProps := make(map[string]any, len(props))
+		for name, prop := range props {
+			repairedProps[name] = repairSchemaValue(prop, strict)
+			if optional[name] {
+				repairedProps[name] = nullableSchema(repairedProps[name])
+			}
+		}
+		repaired["properties"] = repairedProps
+	}
+	for _, key := range []string{"$defs", "definitions"} {
+		if defs, ok := repaired[key].(map[string]any); ok {
+			repairedDefs := make(map[string]any, len(defs))
+			for name, def := range defs {
+				repairedDefs[name] = repairSchemaValue(def, strict)
+			}
+			repaired[key] = repairedDefs
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		if nested, ok := repaired[key].(map[string]any); ok {
+			repaired[key] = repairSchema(nested, strict)
+		}
+	}
+	for _, key := range []string{"anyOf", "allOf", "oneOf"} {
+		if list, ok := repaired[key].([]any); ok {
+			repairedList := make([]any, len(list))
+			for i, item := range list {
+				repairedList[i] = repairSchemaValue(item, strict)
+			}
+			repaired[key] = repairedList
+		}
+	}
+
+	return repaired
+}
+
+// repairSchemaValue repairs a nested schema value, leaving non-schema values untouched
+func repairSchemaValue(value any, strict bool) any {
+	if nested, ok := value.(map[string]any); ok {
+		return repairSchema(nested, strict)
+	}
+	return value
+}
+
+// requiredNames returns the property names of a required list, decoded from JSON or not
+func requiredNames(required any) []string {
+	switch list := required.(type) {
+	case []string:
+		return list
+	case []any:
+		names := make([]string, 0, len(list))
+		for _, v := range list {
+			if name, ok := v.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// nullableSchema returns a copy of a property schema that also accepts null. A single type
+// becomes a [type, "null"] list and an enum gets null as a value; a schema without a type
+// (e.g. a $ref or anyOf) is wrapped in an anyOf with null.
+func nullableSchema(value any) any {
+	schema, ok := value.(map[string]any)
+	if !ok {
+		return value
+	}
+	nullable := make(map[string]any, len(schema))
+	for key, v := range schema {
+		nullable[key] = v
+	}
+
+	switch t := schema["type"].(type) {
+	case string:
+		if t == "null" {
+			return nullable
+		}
+		nullable["type"] = []any{t, "null"}
+	case []any:
+		if !slices.Contains(t, any("null")) {
+			nullable["type"] = append(slices.Clone(t), "null")
+		}
+	case []string:
+		if !slices.Contains(t, "null") {
+			nullable["type"] = append(slices.Clone(t), "null")
+		}
+	default:
+		return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, nil) {
+		nullable["enum"] = append(slices.Clone(enum), nil)
+	}
+	return nullable
+}
+
+// dropRepairNulls removes from the tool requests of a response the null arguments the model
+// sent for properties repairToolSchema made nullable, so tools receive input matching their
+// own schema: an optional property is left out rather than null. No-op unless
+// AutoToolSchemaRepair is set.
+func (a *AzureAIFoundry) dropRepairNulls(input *ai.ModelRequest, resp *ai.ModelResponse) {
+	if !a.AutoToolSchemaRepair || resp == nil {
+		return
+	}
+	for _, msg := range responseMessages(resp) {
+		for _, part := range msg.Content {
+			if !part.IsToolRequest() {
+				continue
+			}
+			idx := slices.IndexFunc(input.Tools, func(tool *ai.ToolDefinition) bool { return tool.Name == part.ToolRequest.Name })
+			if idx >= 0 {
+				dropOptionalNulls(part.ToolRequest.Input, input.Tools[idx].InputSchema)
+			}
+		}
+	}
+}
+
+// dropOptionalNulls deletes, in place, the null values of properties that are optional in
+// schema and don't accept null, in value and the objects nested in it
+func dropOptionalNulls(value any, schema map[string]any) {
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		required := requiredNames(schema["required"])
+		for name, item := range v {
+			sub, _ := props[name].(map[string]any)
+			if item == nil && !slices.Contains(required, name) && !acceptsNull(sub) {
+				delete(v, name)
+				continue
+			}
+			dropOptionalNulls(item, sub)
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for _, item := range v {
+			dropOptionalNulls(item, items)
+		}
+	}
+}
+
+// acceptsNull reports whether a property schema declares null as a type
+func acceptsNull(schema map[string]any) bool {
+	switch t := schema["type"].(type) {
+	case string:
+		return t == "null"
+	case []any:
+		return slices.Contains(t, any("null"))
+	case []string:
+		return slices.Contains(t, "null")
+	}
+	return false
+}
+
+// strictCompatible reports whether a schema can be closed for strict mode: no object in it
+// takes arbitrary keys described by a schema (Go map types)
+func strictCompatible(value any) bool {
+	schema, ok := value.(map[string]any)
+	if !ok {
+		return true
+	}
+	if _, isSchema := schema["additionalProperties"].(map[string]any); isSchema {
+		return false
+	}
+	for _, key := range []string{"properties", "$defs", "definitions"} {
+		named, _ := schema[key].(map[string]any)
+		for _, nested := range named {
+			if !strictCompatible(nested) {
+				return false
+			}
+		}
+	}
+	for _, key := range []string{"anyOf", "allOf", "oneOf"} {
+		list, _ := schema[key].([]any)
+		for _, item := range list {
+			if !strictCompatible(item) {
+				return false
+			}
+		}
+	}
+	return strictCompatible(schema["items"])
+}
+
+// isObjectSchema reports whether a schema describes a JSON object
+func isObjectSchema(schema map[string]any) bool {
+	switch t := schema["type"].(type) {
+	case string:
+		return t == "object"
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s == "object" {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range t {
+			if s == "object" {
+				return true
+			}
+		}
+	}
+	_, hasProps := schema["properties"]
+	return hasProps
+}
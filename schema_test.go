@@ -0,0 +1,284 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestRepairToolSchema(t *testing.T) {
+	tests := []struct {
+		name       string
+		schema     map[string]any
+		want       map[string]any
+		wantStrict bool
+	}{
+		{
+			name: "closes objects, requires all properties and strips keywords",
+			schema: map[string]any{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type":    "object",
+				"properties": map[string]any{
+					"city":  map[string]any{"type": "string", "default": "Madrid", "minLength": 1},
+					"units": map[string]any{"type": "string", "enum": []any{"c", "f"}},
+					"where": map[string]any{
+						"type":       "object",
+						"properties": map[string]any{"lat": map[string]any{"type": "number", "minimum": -90}},
+					},
+				},
+				"required": []any{"city"},
+			},
+			want: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"city":  map[string]any{"type": "string"},
+					"units": map[string]any{"type": []any{"string", "null"}, "enum": []any{"c", "f", nil}},
+					"where": map[string]any{
+						"type":                 []any{"object", "null"},
+						"properties":           map[string]any{"lat": map[string]any{"type": []any{"number", "null"}}},
+						"additionalProperties": false,
+						"required":             []string{"lat"},
+					},
+				},
+				"required":             []string{"city", "units", "where"},
+				"additionalProperties": false,
+			},
+			wantStrict: true,
+		},
+		{
+			name: "additionalProperties true is closed",
+			schema: map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"q": map[string]any{"type": "string"}},
+				"additionalProperties": true,
+			},
+			want: map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"q": map[string]any{"type": []any{"string", "null"}}},
+				"additionalProperties": false,
+				"required":             []string{"q"},
+			},
+			wantStrict: true,
+		},
+		{
+			name: "map input keeps its value schema and keywords",
+			schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"labels": map[string]any{
+						"type":                 "object",
+						"additionalProperties": map[string]any{"type": "string", "maxLength": 64},
+					},
+					"limit": map[string]any{"type": "integer", "minimum": 1},
+				},
+			},
+			want: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"labels": map[string]any{
+						"type":                 "object",
+						"additionalProperties": map[string]any{"type": "string", "maxLength": 64},
+					},
+					"limit": map[string]any{"type": "integer", "minimum": 1},
+				},
+				"additionalProperties": false,
+			},
+			wantStrict: false,
+		},
+		{
+			name: "array items are repaired",
+			schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"tags": map[string]any{
+						"type":     "array",
+						"maxItems": 3,
+						"items": map[string]any{
+							"type":       "object",
+							"properties": map[string]any{"name": map[string]any{"type": "string"}},
+						},
+					},
+				},
+			},
+			want: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"tags": map[string]any{
+						"type": []any{"array", "null"},
+						"items": map[string]any{
+							"type":                 "object",
+							"properties":           map[string]any{"name": map[string]any{"type": []any{"string", "null"}}},
+							"additionalProperties": false,
+							"required":             []string{"name"},
+						},
+					},
+				},
+				"additionalProperties": false,
+				"required":             []string{"tags"},
+			},
+			wantStrict: true,
+		},
+		{
+			name: "optional properties accept null",
+			schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query":  map[string]any{"type": "string"},
+					"limit":  map[string]any{"type": "integer"},
+					"sort":   map[string]any{"type": []any{"string", "null"}},
+					"filter": map[string]any{"$ref": "#/$defs/filter"},
+				},
+				"required": []any{"query"},
+			},
+			want: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{"type": "string"},
+					"limit": map[string]any{"type": []any{"integer", "null"}},
+					"sort":  map[string]any{"type": []any{"string", "null"}},
+					"filter": map[string]any{"anyOf": []any{
+						map[string]any{"$ref": "#/$defs/filter"},
+						map[string]any{"type": "null"},
+					}},
+				},
+				"required":             []string{"filter", "limit", "query", "sort"},
+				"additionalProperties": false,
+			},
+			wantStrict: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original, err := json.Marshal(tt.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, strict := repairToolSchema(tt.schema)
+			if strict != tt.wantStrict {
+				t.Errorf("strict = %v, want %v", strict, tt.wantStrict)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("repairToolSchema() =\n%v\nwant\n%v", got, tt.want)
+			}
+
+			after, _ := json.Marshal(tt.schema)
+			if string(after) != string(original) {
+				t.Errorf("input schema was modified: %s", after)
+			}
+		})
+	}
+}
+
+func TestAutoToolSchemaRepairSendsStrictTools(t *testing.T) {
+	a := &AzureAIFoundry{AutoToolSchemaRepair: true}
+	input := &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("weather?")},
+		Tools: []*ai.ToolDefinition{
+			{
+				Name: "weather",
+				InputSchema: map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"city": map[string]any{"type": "string", "default": "Madrid"}},
+				},
+			},
+			{
+				Name: "tag",
+				InputSchema: map[string]any{
+					"type":                 "object",
+					"additionalProperties": map[string]any{"type": "string"},
+				},
+			},
+		},
+	}
+
+	params, err := a.buildChatCompletionParams(input, "gpt-4o", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params.Tools) != 2 {
+		t.Fatalf("got %d tools, want 2", len(params.Tools))
+	}
+
+	weather := params.Tools[0].OfFunction.Function
+	if !weather.Strict.Valid() || !weather.Strict.Value {
+		t.Errorf("weather tool not sent strict")
+	}
+	if _, hasDefault := weather.Parameters["properties"].(map[string]any)["city"].(map[string]any)["default"]; hasDefault {
+		t.Errorf("default keyword not stripped: %v", weather.Parameters)
+	}
+
+	tag := params.Tools[1].OfFunction.Function
+	if tag.Strict.Valid() {
+		t.Errorf("map-typed tool sent strict")
+	}
+	if _, ok := tag.Parameters["additionalProperties"].(map[string]any); !ok {
+		t.Errorf("map value schema replaced: %v", tag.Parameters)
+	}
+}
+
+func TestAutoToolSchemaRepairDropsOptionalNulls(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// In strict mode the model has to send every property, null for the ones it leaves out
+		completion := chatCompletion("")
+		choice := completion["choices"].([]any)[0].(map[string]any)
+		choice["finish_reason"] = "tool_calls"
+		choice["message"] = map[string]any{"role": "assistant", "content": nil, "tool_calls": []any{map[string]any{
+			"id": "call_1", "type": "function",
+			"function": map[string]any{"name": "search", "arguments": `{"query":"go","limit":null,"page":{"size":null}}`},
+		}}}
+		writeJSON(w, http.StatusOK, completion)
+	})
+	a := newTestPlugin(t, server, func(a *AzureAIFoundry) {
+		a.AutoToolSchemaRepair = true
+		a.ValidateToolArguments = true
+	})
+
+	resp, err := a.generateText(context.Background(), "gpt-4o", modelSettings{}, &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("search go")},
+		Tools: []*ai.ToolDefinition{{
+			Name: "search",
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{"type": "string"},
+					"limit": map[string]any{"type": "integer"},
+					"page": map[string]any{
+						"type":       "object",
+						"properties": map[string]any{"size": map[string]any{"type": "integer"}},
+					},
+				},
+				"required": []any{"query"},
+			},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := resp.ToolRequests()
+	want := map[string]any{"query": "go", "page": map[string]any{}}
+	if len(requests) != 1 || !reflect.DeepEqual(requests[0].Input, want) {
+		t.Errorf("tool requests = %+v, want input %v", requests, want)
+	}
+}
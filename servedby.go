@@ -0,0 +1,55 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"net/http"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// servedByHeaders maps Azure response headers identifying who served a call to message metadata keys
+var servedByHeaders = []struct {
+	header string
+	key    string
+}{
+	{"x-ms-region", "region"},              // Azure region that served the call
+	{"x-ms-deployment-name", "deployment"}, // Deployment that served the call
+	{"apim-request-id", "requestId"},       // Request ID for Azure support
+}
+
+// setServedBy records the region, deployment and request ID of the HTTP response that produced
+// resp in the metadata of every candidate message, so latency or failover can be traced to them
+func setServedBy(resp *ai.ModelResponse, httpResp *http.Response) {
+	if resp == nil || httpResp == nil {
+		return
+	}
+
+	messages := responseMessages(resp)
+	for _, h := range servedByHeaders {
+		value := httpResp.Header.Get(h.header)
+		if value == "" {
+			continue
+		}
+		for _, message := range messages {
+			if message.Metadata == nil {
+				message.Metadata = make(map[string]any)
+			}
+			message.Metadata[h.key] = value
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestServedByOnEveryAPI(t *testing.T) {
+	completion := map[string]any{
+		"id": "cmpl-test", "object": "text_completion", "created": 0, "model": "gpt-35-turbo-instruct",
+		"choices": []any{map[string]any{"index": 0, "text": "ok", "finish_reason": "stop", "logprobs": nil}},
+	}
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ms-region", "Sweden Central")
+		w.Header().Set("x-ms-deployment-name", "prod-east")
+		w.Header().Set("apim-request-id", "req-123")
+
+		streaming := decodeRequest(t, r)["stream"] == true
+		switch r.URL.Path {
+		case "/chat/completions":
+			if streaming {
+				writeStream(w, textStream("ok")...)
+				return
+			}
+			writeChatCompletion(w, "ok")
+		case "/responses":
+			if streaming {
+				writeEvents(w, map[string]any{"type": "response.completed", "response": responsesResponse(outputMessage("ok"))})
+				return
+			}
+			writeJSON(w, http.StatusOK, responsesResponse(outputMessage("ok")))
+		case "/completions":
+			if streaming {
+				data, _ := json.Marshal(completion)
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+				return
+			}
+			writeJSON(w, http.StatusOK, completion)
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+	})
+	a := newTestPlugin(t, server, nil)
+
+	want := map[string]any{"region": "Sweden Central", "deployment": "prod-east", "requestId": "req-123"}
+	for _, api := range []string{APIChat, APIResponses, apiCompletions} {
+		for _, streaming := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/streaming=%v", api, streaming), func(t *testing.T) {
+				var cb func(context.Context, *ai.ModelResponseChunk) error
+				if streaming {
+					cb = func(context.Context, *ai.ModelResponseChunk) error { return nil }
+				}
+				resp, err := a.generateText(context.Background(), "gpt-4o", modelSettings{api: api}, &ai.ModelRequest{
+					Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+				}, cb)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for key, value := range want {
+					if got := resp.Message.Metadata[key]; got != value {
+						t.Errorf("metadata %s = %v, want %v", key, got, value)
+					}
+				}
+			})
+		}
+	}
+}
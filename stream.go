@@ -0,0 +1,122 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"iter"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// errStopIteration signals that the consumer of a ModelStream stopped ranging early
+var errStopIteration = errors.New("azureaifoundry: stream iteration stopped")
+
+// streamStopKey is the context key of the signal set up by WithStreamStop
+type streamStopKey struct{}
+
+// WithStreamStop returns a context for a streaming generation and a function that stops
+// it without cancelling ctx. Once stopped, the callback receives no more chunks, the request
+// is closed and the generation returns what was streamed so far with finish reason
+// "interrupted". Works with genkit.Generate as well as GenerateStream.
+func WithStreamStop(ctx context.Context) (context.Context, func()) {
+	signal, stop := context.WithCancel(context.Background())
+	return context.WithValue(ctx, streamStopKey{}, signal), stop
+}
+
+// streamStopSignal returns the stop signal set up by WithStreamStop, or nil
+func streamStopSignal(ctx context.Context) context.Context {
+	signal, _ := ctx.Value(streamStopKey{}).(context.Context)
+	return signal
+}
+
+// streamStopped reports whether the stream of this context was stopped
+func streamStopped(ctx context.Context) bool {
+	signal := streamStopSignal(ctx)
+	return signal != nil && signal.Err() != nil
+}
+
+// ModelStream is a streaming generation consumed with range-over-func.
+//
+//	stream := azurePlugin.GenerateStream(ctx, "gpt-4o", req)
+//	for chunk, err := range stream.Chunks() {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Print(chunk.Text())
+//	}
+//	resp := stream.Response()
+type ModelStream struct {
+	plugin    *AzureAIFoundry
+	ctx       context.Context
+	stop      func()
+	modelName string
+	input     *ai.ModelRequest
+	response  *ai.ModelResponse
+}
+
+// GenerateStream starts a streaming generation against the given model. modelName is a name
+// registered with DefineModel, whose defaults, logging and tracing apply as they do for
+// genkit.Generate, or a deployment name. The request is sent when Chunks is ranged over.
+func (a *AzureAIFoundry) GenerateStream(ctx context.Context, modelName string, input *ai.ModelRequest) *ModelStream {
+	ctx, stop := WithStreamStop(ctx)
+	return &ModelStream{
+		plugin:    a,
+		ctx:       ctx,
+		stop:      stop,
+		modelName: modelName,
+		input:     input,
+	}
+}
+
+// Chunks returns an iterator over the streamed chunks. A non-nil error is yielded
+// at most once, as the last element. Breaking out of the loop cancels the request.
+func (s *ModelStream) Chunks() iter.Seq2[*ai.ModelResponseChunk, error] {
+	return func(yield func(*ai.ModelResponseChunk, error) bool) {
+		ctx, cancel := context.WithCancel(s.ctx)
+		defer cancel()
+
+		resp, err := s.plugin.lookupModel(s.modelName)(ctx, s.input, func(_ context.Context, chunk *ai.ModelResponseChunk) error {
+			if !yield(chunk, nil) {
+				return errStopIteration
+			}
+			return nil
+		})
+		if errors.Is(err, errStopIteration) {
+			return
+		}
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		s.response = resp
+	}
+}
+
+// Stop ends the stream early, e.g. on user action, and is safe to call from another goroutine.
+// Chunks then ends without an error and Response returns what was streamed so far.
+func (s *ModelStream) Stop() {
+	s.stop()
+}
+
+// Response returns the final assembled response once Chunks has been fully consumed. After
+// Stop it holds what was streamed until then. It is nil if the stream failed or the range
+// loop over Chunks was broken out of.
+func (s *ModelStream) Response() *ai.ModelResponse {
+	return s.response
+}
@@ -0,0 +1,305 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+func TestModelStreamChunks(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeStream(w, textStream("Hello", ", ", "world")...)
+	})
+	a := newTestPlugin(t, server, nil)
+
+	stream := a.GenerateStream(context.Background(), "gpt-4o", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	})
+
+	var texts []string
+	for chunk, err := range stream.Chunks() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts = append(texts, chunk.Text())
+	}
+	if got := strings.Join(texts, "|"); got != "Hello|, |world" {
+		t.Errorf("chunks = %q, want Hello|, |world in order", got)
+	}
+
+	resp := stream.Response()
+	if resp == nil {
+		t.Fatal("Response() = nil after the stream was consumed")
+	}
+	if resp.Text() != "Hello, world" {
+		t.Errorf("response text = %q", resp.Text())
+	}
+	if resp.FinishReason != ai.FinishReasonStop {
+		t.Errorf("finish reason = %q, want stop", resp.FinishReason)
+	}
+}
+
+func TestModelStreamBreak(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeStream(w, textStream("one", "two", "three", "four")...)
+	})
+	a := newTestPlugin(t, server, nil)
+
+	stream := a.GenerateStream(context.Background(), "gpt-4o", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("count")},
+	})
+
+	var texts []string
+	for chunk, err := range stream.Chunks() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts = append(texts, chunk.Text())
+		if len(texts) == 2 {
+			break
+		}
+	}
+	if got := strings.Join(texts, "|"); got != "one|two" {
+		t.Errorf("chunks before break = %q, want one|two", got)
+	}
+	if resp := stream.Response(); resp != nil {
+		t.Errorf("Response() after break = %q, want nil", resp.Text())
+	}
+}
+
+func TestModelStreamStop(t *testing.T) {
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, chunk := range textStream("one", "two") {
+			data, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+		// The rest of the answer never comes; the client has to stop
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	a := newTestPlugin(t, server, nil)
+
+	stream := a.GenerateStream(context.Background(), "gpt-4o", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("count")},
+	})
+
+	var texts []string
+	for chunk, err := range stream.Chunks() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts = append(texts, chunk.Text())
+		if len(texts) == 2 {
+			stream.Stop()
+		}
+	}
+	resp := stream.Response()
+	if resp == nil {
+		t.Fatal("Response() = nil after Stop, want the partial response")
+	}
+	if resp.Text() != "onetwo" {
+		t.Errorf("response text after Stop = %q, want onetwo", resp.Text())
+	}
+}
+
+func TestModelStreamStopDuringToolCall(t *testing.T) {
+	toolCall := func(index int, id, name, arguments string) map[string]any {
+		call := map[string]any{"index": index, "function": map[string]any{"arguments": arguments}}
+		if id != "" {
+			call["id"], call["type"] = id, "function"
+			call["function"].(map[string]any)["name"] = name
+		}
+		return streamChunk(map[string]any{"tool_calls": []any{call}}, "")
+	}
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, chunk := range []map[string]any{
+			streamChunk(map[string]any{"content": "Checking both."}, ""),
+			toolCall(0, "call_1", "weather", `{"city":`),
+			toolCall(0, "", "", `"Madrid"}`),
+			toolCall(1, "call_2", "weather", `{"city":"Par`),
+		} {
+			data, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+		// The second call's arguments never finish; the client has to stop
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	a := newTestPlugin(t, server, func(a *AzureAIFoundry) { a.StreamToolCalls = true })
+
+	stream := a.GenerateStream(context.Background(), "gpt-4o", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("Weather in Madrid and Paris?")},
+	})
+	for chunk, err := range stream.Chunks() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, part := range chunk.Content {
+			if part.IsToolRequest() && part.ToolRequest.Ref == "call_2" {
+				stream.Stop()
+			}
+		}
+	}
+
+	resp := stream.Response()
+	if resp == nil {
+		t.Fatal("Response() = nil after Stop, want the partial response")
+	}
+	if resp.FinishReason != ai.FinishReasonInterrupted {
+		t.Errorf("finish reason = %q, want interrupted", resp.FinishReason)
+	}
+	if resp.Text() != "Checking both." {
+		t.Errorf("response text = %q", resp.Text())
+	}
+	// The complete call is kept; the one cut off mid-arguments is dropped
+	requests := resp.ToolRequests()
+	if len(requests) != 1 || requests[0].Ref != "call_1" || !reflect.DeepEqual(requests[0].Input, map[string]any{"city": "Madrid"}) {
+		t.Errorf("tool requests = %+v, want only the complete call_1", requests)
+	}
+}
+
+func TestModelStreamStopOnEveryAPI(t *testing.T) {
+	completionChunk := func(text string) map[string]any {
+		return map[string]any{
+			"id": "cmpl-test", "object": "text_completion", "created": 0, "model": "gpt-35-turbo-instruct",
+			"choices": []any{map[string]any{"index": 0, "text": text, "finish_reason": nil, "logprobs": nil}},
+		}
+	}
+	tests := []struct {
+		name  string
+		model ModelDefinition
+		write func(w http.ResponseWriter)
+	}{
+		{
+			name:  "responses",
+			model: ModelDefinition{Name: "o4-mini", API: APIResponses},
+			write: func(w http.ResponseWriter) {
+				writeEvents(w,
+					map[string]any{"type": "response.output_text.delta", "item_id": "msg_test", "output_index": 0, "content_index": 0, "delta": "one "},
+					map[string]any{"type": "response.output_text.delta", "item_id": "msg_test", "output_index": 0, "content_index": 0, "delta": "two "},
+				)
+			},
+		},
+		{
+			name:  "completions",
+			model: ModelDefinition{Name: "gpt-35-turbo-instruct", Type: "text"},
+			write: func(w http.ResponseWriter) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				for _, text := range []string{"one ", "two "} {
+					data, _ := json.Marshal(completionChunk(text))
+					fmt.Fprintf(w, "data: %s\n\n", data)
+					w.(http.Flusher).Flush()
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				tt.write(w)
+				// The rest of the answer never comes; the client has to stop
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			})
+			g, a := newTestGenkit(t, server, nil)
+			a.DefineModel(g, tt.model, nil)
+
+			stream := a.GenerateStream(context.Background(), tt.model.Name, &ai.ModelRequest{
+				Messages: []*ai.Message{ai.NewUserTextMessage("count")},
+			})
+			for _, err := range stream.Chunks() {
+				if err != nil {
+					t.Fatal(err)
+				}
+				stream.Stop()
+			}
+
+			resp := stream.Response()
+			if resp == nil {
+				t.Fatal("Response() = nil after Stop, want the partial response")
+			}
+			if resp.FinishReason != ai.FinishReasonInterrupted {
+				t.Errorf("finish reason = %q, want interrupted", resp.FinishReason)
+			}
+			if resp.Text() != "one " {
+				t.Errorf("response text = %q, want the text streamed before Stop", resp.Text())
+			}
+		})
+	}
+}
+
+func TestModelStreamUsesDefinedModel(t *testing.T) {
+	var requests []map[string]any
+	server := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, decodeRequest(t, r))
+		writeStream(w, textStream("ok")...)
+	})
+	var logs bytes.Buffer
+	g, a := newTestGenkit(t, server, func(a *AzureAIFoundry) {
+		a.Logger = slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	})
+	a.DefineModel(g, ModelDefinition{Name: "gpt-4o", Type: "chat"}, nil,
+		WithName("precise"),
+		WithDefaults(map[string]interface{}{"temperature": 0.1}),
+	)
+
+	stream := a.GenerateStream(context.Background(), "precise", &ai.ModelRequest{
+		Messages: []*ai.Message{ai.NewUserTextMessage("hi")},
+	})
+	for _, err := range stream.Chunks() {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("server saw %d requests, want 1", len(requests))
+	}
+	if got := requests[0]["model"]; got != "gpt-4o" {
+		t.Errorf("model = %v, want the deployment gpt-4o", got)
+	}
+	if got := requests[0]["temperature"]; got != 0.1 {
+		t.Errorf("temperature = %v, want the model default 0.1", got)
+	}
+	if !strings.Contains(logs.String(), "azureaifoundry: generate") {
+		t.Errorf("no Logger record for the streamed call: %q", logs.String())
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// SystemMessageMerge controls whether several system messages are sent as one
+type SystemMessageMerge string
+
+const (
+	// MergeSystemMessagesNone sends every system message as is (the default)
+	MergeSystemMessagesNone SystemMessageMerge = ""
+	// MergeSystemMessagesConsecutive merges runs of adjacent system messages
+	MergeSystemMessagesConsecutive SystemMessageMerge = "consecutive"
+	// MergeSystemMessagesAll merges every system message into one, placed where the first was
+	MergeSystemMessagesAll SystemMessageMerge = "all"
+)
+
+// validate reports whether the merge mode is known
+func (m SystemMessageMerge) validate() error {
+	switch m {
+	case MergeSystemMessagesNone, MergeSystemMessagesConsecutive, MergeSystemMessagesAll:
+		return nil
+	default:
+		return fmt.Errorf(`MergeSystemMessages must be "", "consecutive" or "all", got %q`, string(m))
+	}
+}
+
+// mergeSystemMessages returns messages with system messages merged according to mode. The
+// merged message holds the text of each one, joined with newlines. Messages without content
+// are dropped, as they would be when sent; the input slice and messages are not modified.
+func mergeSystemMessages(messages []*ai.Message, mode SystemMessageMerge) []*ai.Message {
+	if mode == MergeSystemMessagesNone {
+		return messages
+	}
+
+	out := make([]*ai.Message, 0, len(messages))
+	texts := map[int][]string{} // Index in out of a merged system message -> texts to join
+	merged := -1                // Index in out of the message later system messages join
+	for _, msg := range messages {
+		if len(msg.Content) == 0 {
+			continue
+		}
+		if role, err := messageRole(msg.Role); err != nil || role != ai.RoleSystem {
+			out = append(out, msg)
+			if mode == MergeSystemMessagesConsecutive {
+				merged = -1
+			}
+			continue
+		}
+		if merged < 0 {
+			merged = len(out)
+			out = append(out, &ai.Message{Role: ai.RoleSystem, Metadata: msg.Metadata})
+		}
+		texts[merged] = append(texts[merged], systemText(msg))
+	}
+
+	for i, parts := range texts {
+		out[i].Content = []*ai.Part{ai.NewTextPart(strings.Join(parts, "\n"))}
+	}
+	return out
+}
+
+// systemText concatenates the text parts of a system message, as they are sent unmerged
+func systemText(msg *ai.Message) string {
+	var sb strings.Builder
+	for _, part := range msg.Content {
+		if part.IsText() {
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+
+	"github.com/firebase/genkit/go/ai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// genAISystem identifies Azure OpenAI in the OpenTelemetry gen-ai semantic conventions
+const genAISystem = "az.ai.openai"
+
+// startSpan starts a client span for an Azure call when a Tracer is configured.
+// The returned span is a no-op otherwise.
+func (a *AzureAIFoundry) startSpan(ctx context.Context, operation, modelName string) (context.Context, trace.Span) {
+	if a.Tracer == nil {
+		return ctx, noop.Span{}
+	}
+	return a.Tracer.Start(ctx, operation+" "+modelName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gen_ai.system", genAISystem),
+			attribute.String("gen_ai.operation.name", operation),
+			attribute.String("gen_ai.request.model", modelName),
+			attribute.String("server.address", a.Endpoint),
+		),
+	)
+}
+
+// endGenerationSpan records the outcome of a model call and ends its span
+func endGenerationSpan(span trace.Span, resp *ai.ModelResponse, err error) {
+	defer span.End()
+	if !span.IsRecording() {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	span.SetAttributes(attribute.StringSlice("gen_ai.response.finish_reasons", []string{string(resp.FinishReason)}))
+	if resp.Usage != nil {
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", resp.Usage.InputTokens),
+			attribute.Int("gen_ai.usage.output_tokens", resp.Usage.OutputTokens),
+		)
+	}
+}
+
+// endEmbeddingSpan records the outcome of an embedding call and ends its span
+func endEmbeddingSpan(span trace.Span, req *ai.EmbedRequest, err error) {
+	defer span.End()
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(attribute.Int("azureaifoundry.embed.documents", len(req.Input)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is returned when a call exceeds RequestTimeout or the "timeout" config key
+var ErrTimeout = errors.New("azureaifoundry: request timed out")
+
+// requestTimeout returns the timeout for a call: the "timeout" config key (a duration
+// string such as "30s") overrides the plugin's RequestTimeout
+func (a *AzureAIFoundry) requestTimeout(config any) (time.Duration, error) {
+	configMap, ok := requestConfig(config)
+	if !ok {
+		return a.RequestTimeout, nil
+	}
+	raw, present := configMap["timeout"]
+	if !present {
+		return a.RequestTimeout, nil
+	}
+
+	value, _ := raw.(string)
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("timeout must be a positive duration such as \"30s\", got %v", raw)
+	}
+	return timeout, nil
+}
+
+// withTimeout bounds ctx by timeout; a zero timeout leaves ctx unchanged
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, timeout, ErrTimeout)
+}
+
+// timeoutError reports ErrTimeout when err was caused by the request timeout expiring
+func timeoutError(ctx context.Context, err error) error {
+	if err != nil && errors.Is(context.Cause(ctx), ErrTimeout) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
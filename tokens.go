@@ -0,0 +1,88 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// ErrInputTooLarge is returned when a request's estimated prompt exceeds MaxInputTokens
+var ErrInputTooLarge = errors.New("azureaifoundry: input exceeds MaxInputTokens")
+
+// estimateTokens roughly estimates the tokens in a text (about four characters per token)
+func estimateTokens(text string) int {
+	return len(text)/4 + 1
+}
+
+// estimateJSONTokens estimates the tokens of a value sent as JSON
+func estimateJSONTokens(v any) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return estimateTokens(string(data))
+}
+
+// estimatePromptTokens estimates the prompt tokens of a generation request: message text,
+// tool calls and results, and tool definitions. Media is not counted.
+func estimatePromptTokens(input *ai.ModelRequest) int {
+	var tokens int
+	for _, msg := range input.Messages {
+		tokens += 4 // Per-message overhead
+		for _, part := range msg.Content {
+			switch {
+			case part.IsText():
+				tokens += estimateTokens(part.Text)
+			case part.IsToolRequest():
+				tokens += estimateTokens(part.ToolRequest.Name) + estimateJSONTokens(part.ToolRequest.Input)
+			case part.IsToolResponse():
+				tokens += estimateJSONTokens(part.ToolResponse.Output)
+			}
+		}
+	}
+	for _, tool := range input.Tools {
+		tokens += estimateTokens(tool.Name) + estimateTokens(tool.Description) + estimateJSONTokens(tool.InputSchema)
+	}
+	return tokens
+}
+
+// estimateRequestTokens estimates the quota a generation request consumes the way Azure does:
+// the prompt tokens plus the requested maximum output tokens
+func estimateRequestTokens(input *ai.ModelRequest) int {
+	tokens := estimatePromptTokens(input)
+	if configMap, ok := requestConfig(input.Config); ok {
+		if maxTokens, ok := toInt64(configMap["maxOutputTokens"]); ok && maxTokens > 0 {
+			tokens += int(maxTokens)
+		}
+	}
+	return tokens
+}
+
+// checkInputTokens rejects a request whose estimated prompt exceeds MaxInputTokens
+func (a *AzureAIFoundry) checkInputTokens(input *ai.ModelRequest) error {
+	if a.MaxInputTokens <= 0 {
+		return nil
+	}
+	if estimated := estimatePromptTokens(input); estimated > a.MaxInputTokens {
+		return fmt.Errorf("%w: about %d tokens, limit %d", ErrInputTooLarge, estimated, a.MaxInputTokens)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// ErrInvalidToolArguments matches a *ToolArgumentsError
+var ErrInvalidToolArguments = errors.New("azureaifoundry: invalid tool arguments")
+
+// ToolArgumentsError is returned with ValidateToolArguments when the model calls a tool that
+// was not declared or with arguments that do not match the tool's input schema
+type ToolArgumentsError struct {
+	Tool     string   // Tool name
+	Ref      string   // Tool call ID
+	Problems []string // Each failure, prefixed with the JSON path of the offending value
+}
+
+// Error lists the validation failures
+func (e *ToolArgumentsError) Error() string {
+	return fmt.Sprintf("invalid arguments for tool '%s': %s", e.Tool, strings.Join(e.Problems, "; "))
+}
+
+// Is matches ErrInvalidToolArguments
+func (e *ToolArgumentsError) Is(target error) bool {
+	return target == ErrInvalidToolArguments
+}
+
+// validateToolArguments checks the tool requests of every candidate in a response against
+// the tools declared in the request. It is a no-op unless ValidateToolArguments is set.
+func (a *AzureAIFoundry) validateToolArguments(input *ai.ModelRequest, resp *ai.ModelResponse) error {
+	if !a.ValidateToolArguments || resp == nil {
+		return nil
+	}
+
+	for _, msg := range responseMessages(resp) {
+		for _, part := range msg.Content {
+			if !part.IsToolRequest() {
+				continue
+			}
+			req := part.ToolRequest
+			idx := slices.IndexFunc(input.Tools, func(tool *ai.ToolDefinition) bool { return tool.Name == req.Name })
+			if idx < 0 {
+				return &ToolArgumentsError{Tool: req.Name, Ref: req.Ref, Problems: []string{"tool is not declared in the request"}}
+			}
+			if problems := validateSchema("$", req.Input, input.Tools[idx].InputSchema); len(problems) > 0 {
+				return &ToolArgumentsError{Tool: req.Name, Ref: req.Ref, Problems: problems}
+			}
+		}
+	}
+	return nil
+}
+
+// validateSchema checks a decoded JSON value against the structural subset of JSON Schema that
+// tool input schemas use: type, enum, const, properties, required, additionalProperties, items,
+// anyOf and oneOf. Other keywords are ignored. It returns one message per failure.
+func validateSchema(path string, value any, schema map[string]any) []string {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return matchesType(value, t) }) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonTypeName(value))}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(v any) bool { return jsonEqual(v, value) }) {
+		return []string{fmt.Sprintf("%s: %v is not one of %v", path, value, enum)}
+	}
+	if constant, ok := schema["const"]; ok && !jsonEqual(constant, value) {
+		return []string{fmt.Sprintf("%s: must be %v", path, constant)}
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		options, ok := schema[key].([]any)
+		if !ok {
+			continue
+		}
+		matched := slices.ContainsFunc(options, func(option any) bool {
+			sub, _ := option.(map[string]any)
+			return len(validateSchema(path, value, sub)) == 0
+		})
+		if !matched {
+			return []string{fmt.Sprintf("%s: does not match any allowed schema", path)}
+		}
+	}
+
+	var problems []string
+	switch v := value.(type) {
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s.%s: required property is missing", path, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := properties[name].(map[string]any); ok {
+				problems = append(problems, validateSchema(path+"."+name, v[name], sub)...)
+				continue
+			}
+			if _, declared := properties[name]; declared {
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					problems = append(problems, fmt.Sprintf("%s.%s: unexpected property", path, name))
+				}
+			case map[string]any:
+				problems = append(problems, validateSchema(path+"."+name, v[name], additional)...)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				problems = append(problems, validateSchema(fmt.Sprintf("%s[%d]", path, i), item, items)...)
+			}
+		}
+	}
+	return problems
+}
+
+// schemaTypes returns the types allowed by a "type" keyword (a string or a list of strings)
+func schemaTypes(v any) []string {
+	if t, ok := v.(string); ok {
+		return []string{t}
+	}
+	return schemaStrings(v)
+}
+
+// schemaStrings returns the strings in a schema list, accepting []any and []string
+func schemaStrings(v any) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []any:
+		var out []string
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// matchesType reports whether a decoded JSON value has the given JSON Schema type
+func matchesType(value any, schemaType string) bool {
+	switch schemaType {
+	case "integer":
+		f, ok := toFloat64(value)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := toFloat64(value)
+		return ok
+	default:
+		return jsonTypeName(value) == schemaType
+	}
+}
+
+// jsonTypeName returns the JSON Schema type name of a decoded JSON value
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	if _, ok := toFloat64(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// jsonEqual compares decoded JSON values, treating all numeric types alike
+func jsonEqual(a, b any) bool {
+	fa, okA := toFloat64(a)
+	fb, okB := toFloat64(b)
+	if okA && okB {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/firebase/genkit/go/ai"
+)
+
+// defaultMaxToolIterations is the number of model calls a tool loop may make when MaxIterations is not set
+const defaultMaxToolIterations = 10
+
+// ErrMaxToolIterations is returned when a tool loop is still calling tools after its last allowed model call
+var ErrMaxToolIterations = errors.New("azureaifoundry: tool loop reached maximum iterations")
+
+// ToolExecutor runs a tool request from the model and returns its output
+type ToolExecutor func(ctx context.Context, req *ai.ToolRequest) (any, error)
+
+// ToolLoopOptions configures GenerateWithTools
+type ToolLoopOptions struct {
+	// Executor runs the tool requests (required)
+	Executor ToolExecutor
+	// MaxIterations caps the model calls (default 10)
+	MaxIterations int
+	// OnChunk receives the streamed chunks of every model call; Index is the position of the
+	// message being generated in the conversation. Optional.
+	OnChunk func(ctx context.Context, chunk *ai.ModelResponseChunk) error
+}
+
+// GenerateWithTools runs a tool-calling loop against the given model: it calls the model, runs
+// the requested tools with opts.Executor, sends their results back and repeats until the model
+// answers without tool requests. The tools must be declared in input.Tools. modelName is a name
+// registered with DefineModel, whose defaults, logging and tracing apply, or a deployment name.
+//
+// The final response's History holds the whole conversation, and its Usage sums every call.
+func (a *AzureAIFoundry) GenerateWithTools(ctx context.Context, modelName string, input *ai.ModelRequest, opts ToolLoopOptions) (*ai.ModelResponse, error) {
+	if opts.Executor == nil {
+		return nil, errors.New("tool loop requires an Executor")
+	}
+	maxIterations := opts.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultMaxToolIterations
+	}
+
+	model := a.lookupModel(modelName)
+	req := *input
+	req.Messages = append([]*ai.Message(nil), input.Messages...)
+	usage := &ai.GenerationUsage{}
+
+	for range maxIterations {
+		var cb func(context.Context, *ai.ModelResponseChunk) error
+		if opts.OnChunk != nil {
+			index := len(req.Messages)
+			cb = func(ctx context.Context, chunk *ai.ModelResponseChunk) error {
+				chunk.Index = index
+				chunk.Role = ai.RoleModel
+				return opts.OnChunk(ctx, chunk)
+			}
+		}
+
+		turn := req
+		resp, err := model(ctx, &turn, cb)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Usage != nil {
+			usage.InputTokens += resp.Usage.InputTokens
+			usage.OutputTokens += resp.Usage.OutputTokens
+			usage.TotalTokens += resp.Usage.TotalTokens
+		}
+
+		toolRequests := resp.ToolRequests()
+		if len(toolRequests) == 0 {
+			resp.Request = &turn
+			resp.Usage = usage
+			return resp, nil
+		}
+
+		// Run the requested tools and send their results back
+		var results []*ai.Part
+		for _, toolReq := range toolRequests {
+			output, err := opts.Executor(ctx, toolReq)
+			if err != nil {
+				return nil, fmt.Errorf("tool '%s' failed: %w", toolReq.Name, err)
+			}
+			results = append(results, ai.NewToolResponsePart(&ai.ToolResponse{
+				Name:   toolReq.Name,
+				Ref:    toolReq.Ref,
+				Output: output,
+			}))
+		}
+		req.Messages = append(req.Messages, resp.Message, ai.NewMessage(ai.RoleTool, nil, results...))
+	}
+
+	return nil, fmt.Errorf("%w (%d)", ErrMaxToolIterations, maxIterations)
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Errors returned by the vector helpers
+var (
+	ErrDimensionMismatch = errors.New("azureaifoundry: vector dimensions do not match")
+	ErrZeroVector        = errors.New("azureaifoundry: zero vector")
+)
+
+// DotProduct returns the dot product of two embedding vectors, accumulated in float64
+func DotProduct(a, b []float32) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("%w: %d != %d", ErrDimensionMismatch, len(a), len(b))
+	}
+
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum, nil
+}
+
+// CosineSimilarity returns the cosine similarity of two embedding vectors, in [-1, 1].
+// It returns ErrDimensionMismatch for vectors of different lengths and ErrZeroVector
+// when either vector has zero magnitude.
+func CosineSimilarity(a, b []float32) (float64, error) {
+	dot, err := DotProduct(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	normA, normB := norm(a), norm(b)
+	if normA == 0 || normB == 0 {
+		return 0, ErrZeroVector
+	}
+
+	// Clamp rounding error so callers can rely on the documented range
+	return max(-1, min(1, dot/(normA*normB))), nil
+}
+
+// Normalize returns a unit-length copy of v. The input is not modified.
+// It returns ErrZeroVector when v has zero magnitude.
+func Normalize(v []float32) ([]float32, error) {
+	n := norm(v)
+	if n == 0 {
+		return nil, ErrZeroVector
+	}
+
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / n)
+	}
+	return out, nil
+}
+
+// norm returns the Euclidean magnitude of v
+func norm(v []float32) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	return math.Sqrt(sum)
+}
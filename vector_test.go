@@ -0,0 +1,90 @@
+// Copyright 2025 Xavier Portilla Edo
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azureaifoundry
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestDotProduct(t *testing.T) {
+	got, err := DotProduct([]float32{1, 2, 3}, []float32{4, -5, 6})
+	if err != nil || got != 12 {
+		t.Errorf("DotProduct = %v, %v; want 12", got, err)
+	}
+	if _, err := DotProduct([]float32{1, 2}, []float32{1, 2, 3}); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("mismatched lengths error = %v, want ErrDimensionMismatch", err)
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    []float32
+		want    float64
+		wantErr error
+	}{
+		{name: "identical", a: []float32{1, 2, 3}, b: []float32{1, 2, 3}, want: 1},
+		{name: "scaled", a: []float32{1, 2, 3}, b: []float32{2, 4, 6}, want: 1},
+		{name: "opposite", a: []float32{1, -1}, b: []float32{-1, 1}, want: -1},
+		{name: "orthogonal", a: []float32{1, 0}, b: []float32{0, 1}, want: 0},
+		{name: "45 degrees", a: []float32{1, 0}, b: []float32{1, 1}, want: 1 / math.Sqrt2},
+		{name: "mismatched lengths", a: []float32{1, 2}, b: []float32{1, 2, 3}, wantErr: ErrDimensionMismatch},
+		{name: "zero vector", a: []float32{0, 0}, b: []float32{1, 1}, wantErr: ErrZeroVector},
+		{name: "empty vectors", a: []float32{}, b: []float32{}, wantErr: ErrZeroVector},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CosineSimilarity(tt.a, tt.b)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("CosineSimilarity = %v, want %v", got, tt.want)
+			}
+			if got < -1 || got > 1 {
+				t.Errorf("CosineSimilarity = %v, outside [-1, 1]", got)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	v := []float32{3, 4}
+	got, err := Normalize(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(float64(got[0])-0.6) > 1e-6 || math.Abs(float64(got[1])-0.8) > 1e-6 {
+		t.Errorf("Normalize = %v, want [0.6 0.8]", got)
+	}
+	if v[0] != 3 || v[1] != 4 {
+		t.Errorf("input modified: %v", v)
+	}
+
+	if _, err := Normalize([]float32{0, 0, 0}); !errors.Is(err, ErrZeroVector) {
+		t.Errorf("zero vector error = %v, want ErrZeroVector", err)
+	}
+}